@@ -2,15 +2,24 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
 )
 
 // Since host actions depend on a host file to work on
@@ -62,7 +71,9 @@ func TestHostActions(t *testing.T) {
 			args:           hosts,
 			expectedOutput: "Added host: host1\nAdded host: host2\nAdded host: host3\n",
 			initList:       false,
-			actionFunction: addAction,
+			actionFunction: func(out io.Writer, hostsFile string, args []string) error {
+				return addAction(out, hostsFile, args, scan.HostInfo{})
+			},
 		},
 		{
 			name:           "ListAction",
@@ -102,6 +113,128 @@ func TestHostActions(t *testing.T) {
 	}
 }
 
+func TestListExpandedAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"10.0.0.1-2", "host1", "web[1-2]"}, true)
+	defer cleanup()
+
+	var out bytes.Buffer
+
+	if err := listExpandedAction(&out, tf, nil); err != nil {
+		t.Fatalf("expected no error but got %q\n", err)
+	}
+
+	expectedOutput := "10.0.0.1\n10.0.0.2\nhost1\nweb1\nweb2\n"
+	if out.String() != expectedOutput {
+		t.Errorf("expected %q as a result but got %q\n", expectedOutput, out.String())
+	}
+}
+
+func TestImportExportActions(t *testing.T) {
+	tf, cleanup := setup(t, []string{"host1"}, true)
+	defer cleanup()
+
+	csvFile := filepath.Join(t.TempDir(), "hosts.csv")
+	data := "host,groups,tags,ports\nhost1,,,\nhost2,web,eu,\"80,443\"\n10.0.0.0/40,,,\nhost3,,,1-x\n,db,,\nmy_box,,,\n"
+	if err := os.WriteFile(csvFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+
+	if err := importAction(&out, tf, csvFile, scan.FormatCSV); err != nil {
+		t.Fatalf("expected no error but got %q\n", err)
+	}
+
+	expectedOutput := fmt.Sprintf(`Imported 1 hosts from %s
+Skipped line 6: ,db,,: no host
+Skipped host1: already in the list
+Skipped 10.0.0.0/40: %s
+Skipped host3: host host3: %s
+Skipped my_box: %s:my_box: label my_box contains '_'
+`, csvFile, scan.ErrInvalidPattern, scan.ErrInvalidPort, scan.ErrInvalidHostName)
+
+	// The errors carry details, only compare the start of their lines.
+	outLines, expectedLines := strings.Split(out.String(), "\n"), strings.Split(expectedOutput, "\n")
+	if len(outLines) != len(expectedLines) {
+		t.Fatalf("expected %q as a result but got %q\n", expectedOutput, out.String())
+	}
+
+	for i := range outLines {
+		if !strings.HasPrefix(outLines[i], expectedLines[i]) {
+			t.Errorf("expected line %q, got %q instead\n", expectedLines[i], outLines[i])
+		}
+	}
+
+	out.Reset()
+	var report bytes.Buffer
+
+	if err := exportAction(context.Background(), &out, &report, tf, scan.FormatCSV); err != nil {
+		t.Fatalf("expected no error but got %q\n", err)
+	}
+
+	expectedOutput = "host,groups,tags,ports\nhost1,,,\nhost2,web,eu,\"80,443\"\n"
+	if out.String() != expectedOutput {
+		t.Errorf("expected %q as a result but got %q\n", expectedOutput, out.String())
+	}
+
+	if report.Len() != 0 {
+		t.Errorf("expected no skipped hosts, got %q instead\n", report.String())
+	}
+}
+
+func TestCheckAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"10.0.0.1", "localhost"}, true)
+	defer cleanup()
+
+	var out bytes.Buffer
+
+	if err := checkAction(context.Background(), &out, tf, scan.Options{}); err != nil {
+		t.Fatalf("expected no error but got %q\n", err)
+	}
+
+	expectedOutput := "2 hosts checked, no problems found\n"
+	if out.String() != expectedOutput {
+		t.Errorf("expected %q as a result but got %q\n", expectedOutput, out.String())
+	}
+
+	// Hand edits do not go through the validation of the add command.
+	if err := os.WriteFile(tf, []byte("10.0.0.1\nLocalhost\nlocalhost:22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+
+	err := checkAction(context.Background(), &out, tf, scan.Options{})
+	if !errors.Is(err, ErrHostsProblems) {
+		t.Fatalf("expected error %q, got %q instead\n", ErrHostsProblems, err)
+	}
+
+	expectedOutput = fmt.Sprintf("Localhost: %s:write it as localhost\nlocalhost:22: %s:localhost:22: ports are not part of hosts, add localhost with a port override\n",
+		scan.ErrNotCanonical, scan.ErrInvalidHost)
+	if out.String() != expectedOutput {
+		t.Errorf("expected %q as a result but got %q\n", expectedOutput, out.String())
+	}
+
+	// A structured file with invalid and duplicate entries still loads, so that they get reported.
+	structured := "hosts:\n  - name: bad..host\n  - name: 10.0.0.1\n  - name: 10.0.0.1\n    tags: [db]\n  - name: localhost\n    ports: ssh,nope\n"
+	if err := os.WriteFile(tf, []byte(structured), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+
+	err = checkAction(context.Background(), &out, tf, scan.Options{})
+	if !errors.Is(err, ErrHostsProblems) {
+		t.Fatalf("expected error %q, got %q instead\n", ErrHostsProblems, err)
+	}
+
+	expectedOutput = fmt.Sprintf("bad..host: %s:bad..host: empty label\n10.0.0.1: %s:same host as 10.0.0.1\nlocalhost: %s:nope\n",
+		scan.ErrInvalidHostName, scan.ErrDuplicateHost, scan.ErrUnknownService)
+	if out.String() != expectedOutput {
+		t.Errorf("expected %q as a result but got %q\n", expectedOutput, out.String())
+	}
+}
+
 // Integration test
 // The goal is to execute all commands in sequence, simulating what a user would do.
 // Flow: Add 3 hosts, list them and delete one host from the list.
@@ -152,7 +285,7 @@ func TestIntegration(t *testing.T) {
 	// Execute all operations in defined sequence add > list > delete > list.
 
 	// Add hosts to the list.
-	if err := addAction(&out, tempFileName, hosts); err != nil {
+	if err := addAction(&out, tempFileName, hosts, scan.HostInfo{}); err != nil {
 		t.Fatalf("expected no error from addAction but got %q instead", err)
 	}
 
@@ -171,7 +304,7 @@ func TestIntegration(t *testing.T) {
 		t.Fatalf("expected no error from listAction after deletion but got %q instead", err)
 	}
 
-	if err := scanAction(&out, tempFileName, nil); err != nil {
+	if err := scanAction(context.Background(), &out, tempFileName, scanConfig{output: outputText}); err != nil {
 		t.Fatalf("expected no error but got %n instead\n", err)
 	}
 
@@ -184,7 +317,7 @@ func TestIntegration(t *testing.T) {
 func TestScanAction(t *testing.T) {
 	hosts := []string{
 		"localhost",
-		"unknownHostOutThere",
+		"unknownhostoutthere",
 	}
 
 	// Setup scan test
@@ -221,19 +354,607 @@ func TestScanAction(t *testing.T) {
 	// Define expected output for scan action.
 	expectedOutput := fmt.Sprintln("localhost:")
 
-	expectedOutput += fmt.Sprintf("\t%d: open\n", ports[0])
-	expectedOutput += fmt.Sprintf("\t%d: closed\n", ports[1])
+	expectedOutput += fmt.Sprintf("\t%d: open (syn-ack, <latency>)\n", ports[0])
+	expectedOutput += fmt.Sprintf("\t%d: closed (conn-refused, <latency>)\n", ports[1])
 	expectedOutput += fmt.Sprintln()
-	expectedOutput += fmt.Sprintln("unknownHostOutThere: Host not found")
+	expectedOutput += fmt.Sprintln("unknownhostoutthere: Host not found")
 	expectedOutput += fmt.Sprintln()
 
 	var out bytes.Buffer
 
-	if err := scanAction(&out, tf, ports); err != nil {
+	if err := scanAction(context.Background(), &out, tf, scanConfig{ports: ports, output: outputText}); err != nil {
 		t.Fatalf("expected no error, but got %q\n", err)
 	}
 
-	if out.String() != expectedOutput {
+	// The latency changes on every run, only verify that it is a duration.
+	output := latencyPattern.ReplaceAllString(out.String(), ", <latency>)")
+	if output != expectedOutput {
 		t.Errorf("expected output %s but got %s instead\n", expectedOutput, out.String())
 	}
 }
+
+// latencyPattern matches the latency which ends the details of a port in the text output.
+var latencyPattern = regexp.MustCompile(`, [0-9.]+(µs|ms|s)\)`)
+
+func TestScanActionOutput(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost", "unknownhostoutthere"}, true)
+	defer cleanup()
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	testCases := []struct {
+		name   string
+		output string
+		verify func(t *testing.T, out []byte)
+	}{
+		{
+			name:   "JSON",
+			output: outputJSON,
+			verify: func(t *testing.T, out []byte) {
+				var res []map[string]any
+				if err := json.Unmarshal(out, &res); err != nil {
+					t.Fatal(err)
+				}
+
+				if len(res) != 2 {
+					t.Fatalf("expected 2 hosts, got %d instead\n", len(res))
+				}
+
+				ports := res[0]["ports"].([]any)
+				state := ports[0].(map[string]any)["state"]
+				if state != "open" {
+					t.Errorf("expected state %q, got %v instead\n", "open", state)
+				}
+
+				if res[1]["notFound"] != true {
+					t.Errorf("expected second host not to be found\n")
+				}
+			},
+		},
+		{
+			name:   "CSV",
+			output: outputCSV,
+			verify: func(t *testing.T, out []byte) {
+				records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expected := [][]string{
+					{
+						"host", "address", "found", "status", "port", "protocol", "state",
+						"reason", "latency_ms", "service", "version", "banner",
+						"tls_version", "tls_cipher", "tls_subject", "tls_issuer", "tls_sans", "tls_not_after",
+						"http_url", "http_status", "http_server", "http_title", "http_redirects", "http_response_ms",
+					},
+					{
+						"localhost", "127.0.0.1", "true", "up", strconv.Itoa(port), "tcp", "open", "syn-ack", "", "", "", "",
+						"", "", "", "", "", "",
+						"", "", "", "", "", "",
+					},
+					{
+						"unknownhostoutthere", "", "false", "unknown", "", "", "", "", "", "", "", "",
+						"", "", "", "", "", "",
+						"", "", "", "", "", "",
+					},
+				}
+
+				// The latency changes on every run, only verify that it is a number.
+				if len(records) > 1 && len(records[1]) > 8 {
+					if _, err := strconv.ParseFloat(records[1][8], 64); err != nil {
+						t.Errorf("expected latency to be a number, got %q instead\n", records[1][8])
+					}
+
+					records[1][8] = ""
+				}
+
+				if fmt.Sprint(records) != fmt.Sprint(expected) {
+					t.Errorf("expected records %q, got %q instead\n", expected, records)
+				}
+			},
+		},
+		{
+			name:   "XML",
+			output: outputXML,
+			verify: func(t *testing.T, out []byte) {
+				var run struct {
+					Hosts []struct {
+						Status struct {
+							State string `xml:"state,attr"`
+						} `xml:"status"`
+						Ports []struct {
+							PortID int `xml:"portid,attr"`
+							State  struct {
+								State string `xml:"state,attr"`
+							} `xml:"state"`
+						} `xml:"ports>port"`
+					} `xml:"host"`
+				}
+
+				if err := xml.Unmarshal(out, &run); err != nil {
+					t.Fatal(err)
+				}
+
+				if len(run.Hosts) != 2 {
+					t.Fatalf("expected 2 hosts, got %d instead\n", len(run.Hosts))
+				}
+
+				if run.Hosts[0].Ports[0].PortID != port || run.Hosts[0].Ports[0].State.State != "open" {
+					t.Errorf("expected port %d to be open, got %+v instead\n", port, run.Hosts[0].Ports[0])
+				}
+
+				if run.Hosts[1].Status.State != "down" {
+					t.Errorf("expected unknown host to be down, got %q instead\n", run.Hosts[1].Status.State)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			cfg := scanConfig{ports: []int{port}, output: tc.output}
+			if err := scanAction(context.Background(), &out, tf, cfg); err != nil {
+				t.Fatalf("expected no error, but got %q\n", err)
+			}
+
+			tc.verify(t, out.Bytes())
+		})
+	}
+}
+
+func TestScanActionInvalidOutput(t *testing.T) {
+	var out bytes.Buffer
+
+	err := scanAction(context.Background(), &out, "", scanConfig{output: "yaml"})
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("expected error %q, got %q instead\n", ErrInvalidOutput, err)
+	}
+}
+
+func TestScanActionGroups(t *testing.T) {
+	tf, cleanup := setup(t, nil, false)
+	defer cleanup()
+
+	var out bytes.Buffer
+
+	// localhost is the only host in the prod group, the unknown host must not be scanned.
+	if err := addAction(&out, tf, []string{"localhost"}, scan.HostInfo{Groups: []string{"prod"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := addAction(&out, tf, []string{"unknownhostoutthere"}, scan.HostInfo{Groups: []string{"dev"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+
+	cfg := scanConfig{output: outputText, groups: []string{"prod"}}
+	if err := scanAction(context.Background(), &out, tf, cfg); err != nil {
+		t.Fatalf("expected no error, but got %q\n", err)
+	}
+
+	expectedOutput := "localhost:\n\n"
+	if out.String() != expectedOutput {
+		t.Errorf("expected output %q but got %q instead\n", expectedOutput, out.String())
+	}
+}
+
+func TestHistoryActions(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	historyFile := filepath.Join(t.TempDir(), "pScan.hosts.history")
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	cfg := scanConfig{ports: []int{port}, output: outputText, historyFile: historyFile}
+
+	// Scan twice, closing the port in between.
+	var out bytes.Buffer
+	if err := scanAction(context.Background(), &out, tf, cfg); err != nil {
+		t.Fatalf("expected no error, but got %q\n", err)
+	}
+
+	ln.Close()
+
+	if err := scanAction(context.Background(), &out, tf, cfg); err != nil {
+		t.Fatalf("expected no error, but got %q\n", err)
+	}
+
+	testCases := []struct {
+		name           string
+		action         func(out io.Writer) error
+		expectedOutput *regexp.Regexp
+	}{
+		{
+			name:   "List",
+			action: func(out io.Writer) error { return historyListAction(out, historyFile) },
+			expectedOutput: regexp.MustCompile(
+				`^1: \S+, 1 hosts, 1 open ports\n2: \S+, 1 hosts, 0 open ports\n$`,
+			),
+		},
+		{
+			name:           "Show",
+			action:         func(out io.Writer) error { return historyShowAction(out, historyFile, 1, outputText) },
+			expectedOutput: regexp.MustCompile(fmt.Sprintf(`^localhost:\n\t%d: open \(syn-ack, [0-9.]+(µs|ms|s)\)\n\n$`, port)),
+		},
+		{
+			name:   "Diff",
+			action: func(out io.Writer) error { return historyDiffAction(out, historyFile, 1, 2, false) },
+			expectedOutput: regexp.MustCompile(
+				fmt.Sprintf(`^localhost: port %d/tcp closed \(open -> closed\)\n$`, port),
+			),
+		},
+		{
+			name:           "DiffSame",
+			action:         func(out io.Writer) error { return historyDiffAction(out, historyFile, 2, 2, false) },
+			expectedOutput: regexp.MustCompile(`^No changes between scans 2 and 2\n$`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			if err := tc.action(&out); err != nil {
+				t.Fatalf("expected no error, but got %q\n", err)
+			}
+
+			if !tc.expectedOutput.MatchString(out.String()) {
+				t.Errorf("expected output to match %q, got %q instead\n", tc.expectedOutput, out.String())
+			}
+		})
+	}
+
+	if err := historyShowAction(&out, historyFile, 3, outputText); !errors.Is(err, scan.ErrRecordNotFound) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrRecordNotFound, err)
+	}
+
+	// An interrupted scan did not reach localhost, it would look like it disappeared.
+	if _, err := scan.AppendHistory(historyFile, scan.Record{Time: time.Now(), Partial: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+
+	if err := historyDiffAction(&out, historyFile, 2, 3, false); !errors.Is(err, ErrPartialScan) {
+		t.Errorf("expected error %q, got %q instead\n", ErrPartialScan, err)
+	}
+
+	if err := historyDiffAction(&out, historyFile, 2, 3, true); err != nil {
+		t.Fatalf("expected no error, but got %q\n", err)
+	}
+
+	expectedOutput := "Warning: scan 3 is partial, the hosts it did not reach are reported as changes\nlocalhost: host disappeared\n"
+	if out.String() != expectedOutput {
+		t.Errorf("expected output %q, got %q instead\n", expectedOutput, out.String())
+	}
+}
+
+func TestDiscoverAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost", "unknownhostoutthere"}, true)
+	defer cleanup()
+
+	var out bytes.Buffer
+
+	if err := discoverAction(context.Background(), &out, tf, scanConfig{output: outputText}); err != nil {
+		t.Fatalf("expected no error, but got %q\n", err)
+	}
+
+	// The reason depends on whether the ICMP echo or a TCP probe answers first.
+	expected := regexp.MustCompile(`^localhost: up \([a-z-]+\)\nunknownhostoutthere: Host not found\n$`)
+	if !expected.MatchString(out.String()) {
+		t.Errorf("expected output to match %q, got %q instead\n", expected, out.String())
+	}
+}
+
+func TestScanConfigMethod(t *testing.T) {
+	testCases := []struct {
+		name        string
+		method      string
+		expectedErr error
+	}{
+		{"Default", "", nil},
+		{"Banner", scan.MethodBanner, nil},
+		{"Unknown", "syn", scan.ErrUnknownMethod},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addScanFlags(cmd)
+
+			if err := cmd.Flags().Set("method", tc.method); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := scanConfigFromFlags(cmd)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v instead\n", tc.expectedErr, err)
+			}
+
+			if err == nil && (cfg.opts.Prober != nil) != (tc.method != "") {
+				t.Errorf("expected a prober only for an explicit method, got %v instead\n", cfg.opts.Prober)
+			}
+		})
+	}
+}
+
+func TestPrintTextTLS(t *testing.T) {
+	expiry := time.Now().Add(10*24*time.Hour + time.Hour)
+
+	results := []scan.Results{{
+		Host: "web",
+		PortStates: []scan.PortState{{
+			Port:     443,
+			Protocol: scan.ProtocolTCP,
+			State:    scan.StateOpen,
+			TLS: &scan.TLSInfo{
+				Version:     "TLS 1.3",
+				CipherSuite: "TLS_AES_128_GCM_SHA256",
+				Subject:     "CN=web",
+				Issuer:      "CN=ca",
+				NotAfter:    expiry,
+				VerifyError: "x509: certificate signed by unknown authority",
+			},
+		}},
+	}}
+
+	expected := fmt.Sprintf("web:\n\t443: open"+
+		"\n\t\ttls: TLS 1.3 TLS_AES_128_GCM_SHA256"+
+		"\n\t\tcertificate: CN=web, issued by CN=ca, expires %[1]s"+
+		"\n\t\tuntrusted: x509: certificate signed by unknown authority"+
+		"\n\t\twarning: the certificate expires in 10 days, on %[1]s\n\n", expiry.Format(time.DateOnly))
+
+	var out bytes.Buffer
+
+	if err := printResults(&out, results, scanConfig{output: outputText, tlsWarn: 30 * 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q instead\n", expected, out.String())
+	}
+
+	// A certificate expiring later than the warning period is not worth a warning.
+	out.Reset()
+
+	if err := printExpiryWarnings(&out, results, 5*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("expected no warnings, got %q instead\n", out.String())
+	}
+}
+
+func TestPrintTextHTTP(t *testing.T) {
+	results := []scan.Results{{
+		Host: "web",
+		PortStates: []scan.PortState{{
+			Port:     80,
+			Protocol: scan.ProtocolTCP,
+			State:    scan.StateOpen,
+			HTTP: &scan.HTTPInfo{
+				URL:          "http://web:80/",
+				StatusCode:   200,
+				Server:       "nginx",
+				Title:        "Welcome",
+				Redirects:    []string{"https://web/", "https://web/home"},
+				ResponseTime: 12300 * time.Microsecond,
+			},
+		}},
+	}}
+
+	expected := "web:\n\t80: open" +
+		"\n\t\thttp: http://web:80/ 200 OK in 12ms" +
+		"\n\t\tserver: nginx" +
+		"\n\t\ttitle: Welcome" +
+		"\n\t\tredirects: https://web/ -> https://web/home\n\n"
+
+	var out bytes.Buffer
+
+	if err := printResults(&out, results, scanConfig{output: outputText}); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q instead\n", expected, out.String())
+	}
+}
+
+func TestAuditAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	openPort := ln.Addr().(*net.TCPAddr).Port
+
+	// Grab a free port and release it, so that it is closed.
+	closedLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closedPort := closedLn.Addr().(*net.TCPAddr).Port
+	closedLn.Close()
+
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := fmt.Sprintf("rules:\n  - name: local\n    hosts: [localhost]\n    forbidden: %d\n    required: %d\n", openPort, closedPort)
+	if err := os.WriteFile(policyFile, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := scanConfig{output: outputText, opts: scan.Options{NoDiscovery: true}}
+
+	testCases := []struct {
+		name        string
+		failOn      scan.Severity
+		expectedErr error
+	}{
+		{"Fail", scan.SeverityLow, ErrPolicyViolations},
+		{"Pass", scan.SeverityCritical, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := auditAction(context.Background(), &out, tf, policyFile, tc.failOn, cfg)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v instead\n", tc.expectedErr, err)
+			}
+
+			expected := fmt.Sprintf("[high] localhost: port %d/tcp is open, forbidden by local\n"+
+				"[medium] localhost: port %d/tcp is not open (closed), required by local\n"+
+				"1 hosts audited, 2 violations found\n", openPort, closedPort)
+			if out.String() != expected {
+				t.Errorf("expected output %q, got %q instead\n", expected, out.String())
+			}
+		})
+	}
+}
+
+func TestScanActionResume(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// The checkpoint of an interrupted scan, which got to port 1 only.
+	checkpointFile := filepath.Join(t.TempDir(), "scan.checkpoint")
+	resume := []scan.Results{{
+		Host:       "localhost",
+		Address:    "127.0.0.1",
+		PortStates: []scan.PortState{{Port: 1, Protocol: scan.ProtocolTCP, State: scan.StateFiltered, Reason: "no-response"}},
+	}}
+
+	if err := scan.SaveCheckpoint(checkpointFile, scan.Checkpoint{Results: resume}); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := scan.LoadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := scanConfig{
+		ports:  []int{1, port},
+		output: outputText,
+		opts:   scan.Options{NoDiscovery: true, Checkpoint: checkpointFile, Resume: cp.Results},
+	}
+
+	var out bytes.Buffer
+
+	if err := scanAction(context.Background(), &out, tf, cfg); err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	// The resumed port keeps its state, it has no latency since it was not probed in the test.
+	expected := fmt.Sprintf("localhost:\n\t1: filtered (no-response)\n\t%d: open (syn-ack, <latency>)\n\n", port)
+	if latencyPattern.ReplaceAllString(out.String(), ", <latency>)") != expected {
+		t.Errorf("expected output %q, got %q instead\n", expected, out.String())
+	}
+
+	// A complete scan has nothing left to resume.
+	if _, err := os.Stat(checkpointFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the checkpoint to be removed, got %v instead\n", err)
+	}
+}
+
+func TestResultsStream(t *testing.T) {
+	up := func(host, address string) scan.Results {
+		return scan.Results{Host: host, Address: address, Addresses: []string{address}, Status: scan.HostUp}
+	}
+
+	dual := []string{"127.0.0.1", "::1"}
+	dual1, dual2 := up("dual", dual[0]), up("dual", dual[1])
+	dual1.Addresses, dual2.Addresses = dual, dual
+
+	var out bytes.Buffer
+
+	cfg := scanConfig{output: outputText}
+	cfg.opts.AddressMode = scan.AddressAll
+	s := newResultsStream(&out, cfg)
+
+	// Every step adds a host and expects the output printed so far.
+	steps := []struct {
+		index    int
+		results  scan.Results
+		expected string
+	}{
+		{1, dual1, ""},
+		{0, up("a", "10.0.0.1"), "a:\n\n"},
+		{3, up("b", "10.0.0.2"), "a:\n\n"},
+		// Both addresses of dual are printed together, with their labels.
+		{2, dual2, "a:\n\ndual (127.0.0.1):\n\ndual (::1):\n\nb:\n\n"},
+	}
+
+	for _, step := range steps {
+		s.add(step.index, step.results)
+
+		if out.String() != step.expected {
+			t.Fatalf("expected output %q after host %d, got %q instead\n", step.expected, step.index, out.String())
+		}
+	}
+
+	// An interrupted scan prints the hosts scanned partially at the end.
+	final := []scan.Results{up("a", "10.0.0.1"), dual1, dual2, up("b", "10.0.0.2"), up("c", "10.0.0.3")}
+	if err := s.finish(final); err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	expected := steps[len(steps)-1].expected + "c:\n\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q instead\n", expected, out.String())
+	}
+}
+
+func TestRenderProgress(t *testing.T) {
+	testCases := []struct {
+		name     string
+		done     int
+		total    int
+		elapsed  time.Duration
+		expected string
+	}{
+		{"Start", 0, 10, 0, "[>                             ]   0% 0/10"},
+		{"Half", 5, 10, 10 * time.Second, "[===============>              ]  50% 5/10 ETA 10s"},
+		{"Done", 10, 10, 20 * time.Second, "[==============================] 100% 10/10"},
+		{"Empty", 0, 0, 0, "[==============================] 100% 0/0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if line := renderProgress(tc.done, tc.total, tc.elapsed); line != tc.expected {
+				t.Errorf("expected %q, got %q instead\n", tc.expected, line)
+			}
+		})
+	}
+}
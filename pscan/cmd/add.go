@@ -37,30 +37,58 @@ var addCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostsFile := viper.GetString("hosts-file")
 
-		return addAction(os.Stdout, hostsFile, args)
+		groups, err := cmd.Flags().GetStringSlice("group")
+		if err != nil {
+			return err
+		}
+
+		tags, err := cmd.Flags().GetStringSlice("tag")
+		if err != nil {
+			return err
+		}
+
+		ports, err := cmd.Flags().GetString("ports")
+		if err != nil {
+			return err
+		}
+
+		info := scan.HostInfo{Groups: groups, Tags: tags, Ports: ports}
+
+		return addAction(os.Stdout, hostsFile, args, info)
 	},
 }
 
 func init() {
 	hostsCmd.AddCommand(addCmd)
+
+	addCmd.Flags().StringSliceP("group", "g", nil, "groups of the added hosts, e.g. prod")
+	addCmd.Flags().StringSliceP("tag", "t", nil, "tags of the added hosts, e.g. db")
+	addCmd.Flags().StringP("ports", "p", "", "ports to scan on the added hosts instead of the scan ports")
 }
 
 // addAction runs when users use add command to add a host to the host list.
-// PS: The current design is not suitable for concurrent usage, beware of it.
-func addAction(out io.Writer, hostsFile string, args []string) error {
-	hl := &scan.HostsList{}
+// The groups, tags and port overrides in info are set on every added host.
+// The hosts file is updated in a single transaction, so concurrent runs are safe.
+func addAction(out io.Writer, hostsFile string, args []string, info scan.HostInfo) error {
+	return scan.UpdateHostsFile(hostsFile, func(hl *scan.HostsList) error {
+		for _, host := range args {
+			// Report the host as it is saved, see scan.NormalizeHost.
+			host, err := scan.NormalizeHost(host)
+			if err != nil {
+				return err
+			}
 
-	if err := hl.Load(hostsFile); err != nil {
-		return err
-	}
+			if err := hl.Add(host); err != nil {
+				return err
+			}
 
-	for _, host := range args {
-		if err := hl.Add(host); err != nil {
-			return err
-		}
+			if err := hl.SetInfo(host, info); err != nil {
+				return err
+			}
 
-		fmt.Fprintln(out, "Added host:", host)
-	}
+			fmt.Fprintln(out, "Added host:", host)
+		}
 
-	return hl.Save(hostsFile)
+		return nil
+	})
 }
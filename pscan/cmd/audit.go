@@ -0,0 +1,157 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ErrPolicyViolations = errors.New("policy violations found")
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Scan the hosts and check their ports against a policy",
+	Long: `Scans the hosts, then checks their open ports against the rules of a policy file:
+
+    rules:
+      - name: prod
+        groups: [prod]
+        allowed: 22,443
+        required: 443
+      - name: no telnet
+        forbidden: telnet
+        severity: critical
+
+    A rule applies to the hosts, groups and tags it lists, or to every host without them.
+    The required and forbidden ports are always scanned, but the allowed ones only apply to
+    the ports of --ports: with the default 22,80,443, the prod rule above only catches an open
+    port 80. Widen --ports, e.g. to 1-1024, to catch the other ports which are not allowed.
+
+    See docs/examples/policy.yaml for a sample policy.
+
+    The command fails when it finds a violation of the --fail-on severity or above,
+    so that it can gate CI jobs.
+    `,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile := viper.GetString("hosts-file")
+
+		cfg, err := scanConfigFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		failOn, err := scan.ParseSeverity(viper.GetString("fail-on"))
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		return auditAction(ctx, os.Stdout, hostsFile, viper.GetString("policy"), failOn, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	addScanFlags(auditCmd)
+	auditCmd.Flags().StringP("output", "o", outputText, "output format: text or json")
+	auditCmd.Flags().String("policy", "policy.yaml", "policy file to check the hosts against")
+	auditCmd.Flags().String("fail-on", scan.SeverityLow.String(), "lowest severity failing the audit: low, medium, high or critical")
+}
+
+// auditAction scans the hosts and prints the violations of the policy.
+// It fails when a violation is at least as severe as failOn, or when the scan is interrupted,
+// since missing results would hide violations.
+func auditAction(ctx context.Context, out io.Writer, hostsFile, policyFile string, failOn scan.Severity, cfg scanConfig) error {
+	if cfg.output != outputText && cfg.output != outputJSON {
+		return fmt.Errorf("%w:%q, use one of text or json", ErrInvalidOutput, cfg.output)
+	}
+
+	policy, err := scan.LoadPolicy(policyFile)
+	if err != nil {
+		return err
+	}
+
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	hl = hl.Select(cfg.groups, cfg.tags)
+
+	ports := append(slices.Clone(cfg.ports), policy.Ports()...)
+	slices.Sort(ports)
+
+	results, err := scan.RunContext(ctx, hl, slices.Compact(ports), cfg.opts)
+	if err != nil {
+		return fmt.Errorf("scan interrupted, the hosts cannot be audited: %w", err)
+	}
+
+	violations := policy.Audit(hl, results)
+
+	if err := printViolations(out, violations, len(results), cfg.output); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, v := range violations {
+		if v.Severity >= failOn {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w:%d of severity %s or above", ErrPolicyViolations, failed, failOn)
+	}
+
+	return nil
+}
+
+func printViolations(out io.Writer, violations []scan.Violation, hosts int, output string) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(violations)
+	}
+
+	for _, v := range violations {
+		if _, err := fmt.Fprintf(out, "[%s] %s\n", v.Severity, v); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "%d hosts audited, %d violations found\n", hosts, len(violations))
+	return err
+}
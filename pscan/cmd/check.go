@@ -0,0 +1,82 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ErrHostsProblems = errors.New("problems found in the hosts list")
+
+// checkCmd represents the hosts check command
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report the invalid, duplicate and unresolved hosts of the list",
+	Long: `Checks every host of the list, and reports:
+    invalid hosts, such as mistyped addresses or names with a port
+    hosts which are not written in their canonical form, e.g. Example.COM instead of example.com
+    hosts listed more than once under different spellings
+    host names which cannot be resolved, those of the host name patterns included
+
+    The command fails when it finds a problem, so that it can guard hand edited hosts files in scripts.
+    `,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile := viper.GetString("hosts-file")
+
+		return checkAction(cmd.Context(), os.Stdout, hostsFile, scan.Options{})
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(checkCmd)
+}
+
+// checkAction prints the problems of the hosts list, and fails when there is any.
+func checkAction(ctx context.Context, out io.Writer, hostsFile string, opts scan.Options) error {
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	problems, err := scan.CheckHosts(ctx, hl, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		_, err := fmt.Fprintf(out, "%d hosts checked, no problems found\n", len(hl.Hosts))
+		return err
+	}
+
+	for _, p := range problems {
+		if _, err := fmt.Fprintln(out, p); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w:%d in %s", ErrHostsProblems, len(problems), hostsFile)
+}
@@ -0,0 +1,37 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	Long: `Inspects the configuration which the flags, the PSCAN_ environment variables and the config file make up.
+    Print the effective configuration with the show command
+    Check the config file for mistakes with the validate command.
+
+    Every flag of the scan command can be set in the config file by its name,
+    and the profiles key holds named sets of them, which are selected with --profile.
+    `,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
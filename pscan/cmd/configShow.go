@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Prints the configuration the scan command runs with, merging the flag defaults,
+the config file, the PSCAN_ environment variables and the selected profile.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Show the settings of the scan command, with their defaults.
+		if err := bindFlags(scanCmd); err != nil {
+			return err
+		}
+
+		profile, err := cmd.Flags().GetString("profile")
+		if err != nil {
+			return err
+		}
+
+		if profile == "" {
+			profile = viper.GetString("profile")
+		}
+
+		if err := applyProfile(profile); err != nil {
+			return err
+		}
+
+		return configShowAction(os.Stdout)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+
+	configShowCmd.Flags().String("profile", "", "show the configuration with this profile applied")
+}
+
+// configShowAction prints the effective configuration as YAML, together with every available profile.
+func configShowAction(out io.Writer) error {
+	settings := viper.AllSettings()
+	settings["profiles"] = profiles()
+
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(settings); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var ErrNoConfigFile = errors.New("no config file found, pass one with --config")
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:          "validate [config file]",
+	Short:        "Check the config file for mistakes",
+	Long:         `Checks that every setting of the config file and of its profiles is a known flag with a valid value.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := viper.ConfigFileUsed()
+		if len(args) == 1 {
+			file = args[0]
+		}
+
+		return configValidateAction(os.Stdout, file, knownSettings())
+	},
+}
+
+// knownSettings returns the flags which can be set in the config file, those of the commands reading Viper.
+func knownSettings() *pflag.FlagSet {
+	known := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	known.AddFlagSet(rootCmd.PersistentFlags())
+
+	for _, c := range []*cobra.Command{scanCmd, watchCmd, discoverCmd, auditCmd, serveCmd} {
+		known.AddFlagSet(c.LocalFlags())
+	}
+
+	return known
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// configValidateAction checks the settings of the config file against the known flags,
+// and the settings of its profiles against the flags of the scan command.
+func configValidateAction(out io.Writer, file string, known *pflag.FlagSet) error {
+	if file == "" {
+		return ErrNoConfigFile
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+
+	settings := v.AllSettings()
+
+	profileSettings := v.GetStringMap("profiles")
+	delete(settings, "profiles")
+
+	errs := []error{validateSettings(settings, known)}
+
+	names := make([]string, 0, len(profileSettings))
+	for name := range profileSettings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		profile, ok := profileSettings[name].(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w:profile %s must be a map of settings", ErrInvalidSetting, name))
+			continue
+		}
+
+		if err := validateSettings(profile, profileFlags); err != nil {
+			errs = append(errs, fmt.Errorf("profile %s: %w", name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s is not valid:\n%w", file, err)
+	}
+
+	_, err := fmt.Fprintf(out, "%s is valid\n", file)
+	return err
+}
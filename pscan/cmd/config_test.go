@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestScanConfigProfile(t *testing.T) {
+	// Profiles are merged into the global configuration, start over once the test is done.
+	t.Cleanup(viper.Reset)
+
+	top10, err := scan.ParsePorts("top10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name          string
+		flags         map[string]string
+		expectedPorts []int
+		expectedErr   error
+	}{
+		{"Profile", map[string]string{"profile": "quick"}, top10, nil},
+		{"FlagOverridesProfile", map[string]string{"profile": "quick", "ports": "8080"}, []int{8080}, nil},
+		{"UnknownProfile", map[string]string{"profile": "slow"}, nil, ErrUnknownProfile},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Reset()
+
+			cmd := &cobra.Command{}
+			addScanFlags(cmd)
+
+			for name, value := range tc.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := scanConfigFromFlags(cmd)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v instead\n", tc.expectedErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !slices.Equal(cfg.ports, tc.expectedPorts) {
+				t.Errorf("expected ports %v, got %v instead\n", tc.expectedPorts, cfg.ports)
+			}
+
+			if cfg.opts.Timeout != 500*time.Millisecond {
+				t.Errorf("expected the timeout of the profile, got %s instead\n", cfg.opts.Timeout)
+			}
+		})
+	}
+}
+
+func TestScanConfigEnv(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Reset()
+
+	viper.SetEnvPrefix("PSCAN")
+	viper.AutomaticEnv()
+
+	t.Setenv("PSCAN_PORTS", "22,25")
+	t.Setenv("PSCAN_GROUP", "web,db")
+
+	cmd := &cobra.Command{}
+	addScanFlags(cmd)
+
+	cfg, err := scanConfigFromFlags(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(cfg.ports, []int{22, 25}) {
+		t.Errorf("expected ports %v, got %v instead\n", []int{22, 25}, cfg.ports)
+	}
+
+	if !slices.Equal(cfg.groups, []string{"web", "db"}) {
+		t.Errorf("expected groups %v, got %v instead\n", []string{"web", "db"}, cfg.groups)
+	}
+}
+
+func TestConfigValidateAction(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      string
+		expectedErr error
+	}{
+		{
+			name:   "Valid",
+			config: "hosts-file: x.hosts\ntimeout: 2s\nprofiles:\n  web:\n    ports: http,https\n    tls: true\n",
+		},
+		{
+			name:        "UnknownSetting",
+			config:      "colour: blue\n",
+			expectedErr: ErrInvalidSetting,
+		},
+		{
+			name:        "InvalidValue",
+			config:      "workers: lots\n",
+			expectedErr: ErrInvalidSetting,
+		},
+		{
+			name:        "InvalidProfile",
+			config:      "profiles:\n  web:\n    ports: 99999\n",
+			expectedErr: scan.ErrInvalidPort,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(file, []byte(tc.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+
+			err := configValidateAction(&out, file, knownSettings())
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v instead\n", tc.expectedErr, err)
+			}
+
+			if err == nil && out.String() != file+" is valid\n" {
+				t.Errorf("expected output %q, got %q instead\n", file+" is valid\n", out.String())
+			}
+		})
+	}
+
+	if err := configValidateAction(&bytes.Buffer{}, "", knownSettings()); !errors.Is(err, ErrNoConfigFile) {
+		t.Errorf("expected error %q, got %q instead\n", ErrNoConfigFile, err)
+	}
+}
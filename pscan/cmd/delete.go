@@ -44,25 +44,17 @@ func init() {
 }
 
 // deleteAction runs when users use delete command to remove a host to the host list.
-// PS: The current design is not suitable for concurrent usage, beware of it.
+// The hosts file is updated in a single transaction, so concurrent runs are safe.
 func deleteAction(out io.Writer, hostsFile string, args []string) error {
-	hl := &scan.HostsList{}
+	return scan.UpdateHostsFile(hostsFile, func(hl *scan.HostsList) error {
+		for _, host := range args {
+			if err := hl.Remove(host); err != nil {
+				return err
+			}
 
-	if err := hl.Load(hostsFile); err != nil {
-		return err
-	}
-
-	for _, host := range args {
-		if err := hl.Remove(host); err != nil {
-			return err
+			fmt.Fprintln(out, "Removed the host:", host)
 		}
 
-		fmt.Fprintln(out, "Removed the host:", host)
-	}
-
-	if err := hl.Save(hostsFile); err != nil {
-		return err
-	}
-
-	return nil
+		return nil
+	})
 }
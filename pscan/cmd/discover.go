@@ -0,0 +1,152 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// discoverCmd represents the discover command
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Check which hosts are up without scanning their ports",
+	Long: `Checks which hosts are up by sending them an ICMP echo request, when the privileges allow it,
+and TCP connect probes to a few common ports. A host is up as soon as it answers any of them,
+a refused connection included.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile := viper.GetString("hosts-file")
+
+		if err := bindFlags(cmd); err != nil {
+			return err
+		}
+
+		cfg := scanConfig{
+			output: viper.GetString("output"),
+			groups: stringSlice("group"),
+			tags:   stringSlice("tag"),
+		}
+
+		cfg.opts.Workers = viper.GetInt("workers")
+		politeness(&cfg.opts)
+
+		var err error
+		if cfg.opts.DiscoveryPorts, err = discoveryPorts(); err != nil {
+			return err
+		}
+
+		if cfg.opts.AddressMode, err = addressMode(); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		return discoverAction(ctx, os.Stdout, hostsFile, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(discoverCmd)
+
+	addDiscoveryFlags(discoverCmd)
+	addAddressFlags(discoverCmd)
+	discoverCmd.Flags().IntP("workers", "w", scan.DefaultWorkers, "number of concurrent discovery workers")
+	discoverCmd.Flags().StringSliceP("group", "g", nil, "only check the hosts in any of these groups")
+	discoverCmd.Flags().StringSliceP("tag", "t", nil, "only check the hosts with any of these tags")
+	discoverCmd.Flags().Duration("timeout", scan.DefaultTimeout, "how long a probe waits for an answer")
+	discoverCmd.Flags().Int("rate", 0, "maximum number of probes per second, 0 means unlimited")
+	discoverCmd.Flags().StringP("output", "o", outputText, "output format: text, json, csv or xml")
+}
+
+// addDiscoveryFlags defines the flags which tune how the hosts are checked to be up.
+func addDiscoveryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("discovery-ports", "", "TCP ports probed to check whether a host is up, e.g. 80,443")
+}
+
+// discoveryPorts returns the ports of the discovery-ports setting, nil means the default ports.
+func discoveryPorts() ([]int, error) {
+	spec := viper.GetString("discovery-ports")
+	if spec == "" {
+		return nil, nil
+	}
+
+	return scan.ParsePorts(spec)
+}
+
+// discoverAction checks which hosts of the hosts file are up.
+// If ctx ends before every host is checked, the partial results are printed
+// before the interruption is reported.
+func discoverAction(ctx context.Context, out io.Writer, hostsFile string, cfg scanConfig) error {
+	if err := validateOutput(cfg.output); err != nil {
+		return err
+	}
+
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	results, discoverErr := scan.Discover(ctx, hl.Select(cfg.groups, cfg.tags), cfg.opts)
+
+	var err error
+	if cfg.output == outputText {
+		err = printDiscovery(out, results)
+	} else {
+		err = printResults(out, results, cfg)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if discoverErr != nil {
+		return fmt.Errorf("discovery interrupted, the results are partial: %w", discoverErr)
+	}
+
+	return nil
+}
+
+// printDiscovery writes one line per host with its status and the reason of it.
+func printDiscovery(out io.Writer, results []scan.Results) error {
+	labels := hostLabels(results)
+
+	for i, r := range results {
+		line := fmt.Sprintf("%s: %s", labels[i], r.Status)
+
+		switch {
+		case r.NotFound:
+			line = fmt.Sprintf("%s: Host not found", labels[i])
+		case r.Reason != "":
+			line += fmt.Sprintf(" (%s)", r.Reason)
+		}
+
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
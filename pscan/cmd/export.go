@@ -0,0 +1,97 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// exportCmd represents the hosts export command
+var exportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write the hosts list as an Nmap, /etc/hosts or CSV file",
+	Long: `Writes the hosts list to the file, or to the standard output without one. The supported formats are:
+    nmap-xml   Nmap XML, which Nmap and pscan import read back
+    etc-hosts  an /etc/hosts entry for every host name, with the address it resolves to
+    csv        a host per row with its groups, tags and ports, which pscan import reads back
+
+    The hosts which cannot be exported are reported on the standard error.
+    `,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile := viper.GetString("hosts-file")
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return exportAction(cmd.Context(), os.Stdout, os.Stderr, hostsFile, format)
+		}
+
+		// Build the whole export first, so that a failing one leaves the existing file untouched.
+		var out bytes.Buffer
+
+		if err := exportAction(cmd.Context(), &out, os.Stderr, hostsFile, format); err != nil {
+			return err
+		}
+
+		return os.WriteFile(args[0], out.Bytes(), 0644)
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String(
+		"format",
+		scan.FormatCSV,
+		fmt.Sprintf("format of the file: %s, %s or %s", scan.FormatNmapXML, scan.FormatEtcHosts, scan.FormatCSV),
+	)
+}
+
+// exportAction writes the hosts list to out in the given format, and the hosts which
+// cannot be exported to report.
+func exportAction(ctx context.Context, out, report io.Writer, hostsFile, format string) error {
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	skipped, err := scan.ExportHosts(ctx, out, hl, format, scan.Options{})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range skipped {
+		if _, err := fmt.Fprintf(report, "Skipped %s\n", s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Inspect the scan history",
+	Long: `Inspects the results of previous scans, which the scan command saves to the history file.
+    List the saved scans with the list command
+    Show the results of a scan with the show command
+    Compare two scans with the diff command.
+    `,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+// parseScanID converts a scan ID given as a command argument.
+func parseScanID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid scan ID %q, use the IDs shown by pscan history list", arg)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+)
+
+// ErrPartialScan is returned when a diff involves an interrupted scan without --allow-partial.
+var ErrPartialScan = errors.New("partial scan")
+
+// historyDiffCmd represents the history diff command
+var historyDiffCmd = &cobra.Command{
+	Use:          "diff <id1> <id2>",
+	Aliases:      []string{"d"},
+	Short:        "Show what changed between two saved scans",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldID, err := parseScanID(args[0])
+		if err != nil {
+			return err
+		}
+
+		newID, err := parseScanID(args[1])
+		if err != nil {
+			return err
+		}
+
+		allowPartial, err := cmd.Flags().GetBool("allow-partial")
+		if err != nil {
+			return err
+		}
+
+		return historyDiffAction(os.Stdout, historyFile(), oldID, newID, allowPartial)
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyDiffCmd)
+
+	historyDiffCmd.Flags().Bool("allow-partial", false, "also compare interrupted scans, the hosts they did not reach show up as disappeared or appeared")
+}
+
+// historyDiffAction prints the hosts which appeared or disappeared and the ports
+// which opened or closed between the scans with oldID and newID.
+// An interrupted scan misses the hosts it did not reach, so it is refused with ErrPartialScan
+// unless allowPartial is set. When it is set, the output starts with a warning about the partial scan.
+func historyDiffAction(out io.Writer, historyFile string, oldID, newID int, allowPartial bool) error {
+	oldRec, err := scan.FindRecord(historyFile, oldID)
+	if err != nil {
+		return err
+	}
+
+	newRec, err := scan.FindRecord(historyFile, newID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range []struct {
+		id  int
+		rec scan.Record
+	}{{oldID, oldRec}, {newID, newRec}} {
+		if !r.rec.Partial {
+			continue
+		}
+
+		if !allowPartial {
+			return fmt.Errorf("%w:scan %d got interrupted, compare it anyway with --allow-partial", ErrPartialScan, r.id)
+		}
+
+		if _, err := fmt.Fprintf(out, "Warning: scan %d is partial, the hosts it did not reach are reported as changes\n", r.id); err != nil {
+			return err
+		}
+	}
+
+	changes := scan.Diff(oldRec.Results, newRec.Results)
+	if len(changes) == 0 {
+		_, err := fmt.Fprintf(out, "No changes between scans %d and %d\n", oldID, newID)
+		return err
+	}
+
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(out, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+)
+
+// historyListCmd represents the history list command
+var historyListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"l"},
+	Short:   "List the saved scans",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return historyListAction(os.Stdout, historyFile())
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyListCmd)
+}
+
+// historyListAction prints a summary line for each scan in the history, oldest first.
+func historyListAction(out io.Writer, historyFile string) error {
+	history, err := scan.LoadHistory(historyFile)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range history {
+		line := fmt.Sprintf(
+			"%d: %s, %d hosts, %d open ports",
+			rec.ID,
+			rec.Time.Format(time.RFC3339),
+			len(rec.Results),
+			rec.OpenPorts(),
+		)
+
+		if rec.Partial {
+			line += " (partial)"
+		}
+
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
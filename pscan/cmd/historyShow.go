@@ -0,0 +1,66 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+)
+
+// historyShowCmd represents the history show command
+var historyShowCmd = &cobra.Command{
+	Use:          "show <id>",
+	Aliases:      []string{"s"},
+	Short:        "Show the results of a saved scan",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseScanID(args[0])
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		return historyShowAction(os.Stdout, historyFile(), id, output)
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyShowCmd)
+
+	historyShowCmd.Flags().StringP("output", "o", outputText, "output format: text, json, csv or xml")
+}
+
+// historyShowAction prints the results of a saved scan like the scan command does.
+func historyShowAction(out io.Writer, historyFile string, id int, output string) error {
+	if err := validateOutput(output); err != nil {
+		return err
+	}
+
+	rec, err := scan.FindRecord(historyFile, id)
+	if err != nil {
+		return err
+	}
+
+	return printResults(out, rec.Results, scanConfig{output: output})
+}
@@ -26,7 +26,17 @@ var hostsCmd = &cobra.Command{
 	Long: `Manages the hosts lists for pScan
     Add hosts with the add command
     Delete hosts with the delete command
-    List hosts with the list command.
+    List hosts with the list command
+    Check the hosts for mistakes with the check command
+    Move hosts from and to other tools with the import and export commands.
+
+    Hosts can be host names, IP addresses, CIDR blocks (192.168.1.0/24),
+    address ranges (10.0.0.1-50) or host name patterns (web[01-10].example.com).
+    Hosts are saved in their canonical form: names are lowercased, addresses are
+    written the standard way and URLs are reduced to their host.
+
+    Hosts added with groups, tags or port overrides are saved in a structured
+    YAML (or JSON, for .json files) hosts file, plain hosts files are still supported.
     `,
 }
 
@@ -0,0 +1,131 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// importCmd represents the hosts import command
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Add the hosts of an Nmap, /etc/hosts, CSV or known_hosts file to the list",
+	Long: `Adds the hosts found in a file of another tool to the list. The supported formats are:
+    nmap-xml         the XML output of Nmap (-oX) or pscan, the hosts which are down are skipped
+    etc-hosts        the /etc/hosts file, the canonical name of every address is added
+    csv              a host per row, from the host column or the first one, with optional groups, tags and ports columns
+    ssh-known-hosts  the OpenSSH known_hosts file, hashed names and wildcards are skipped
+
+    Hosts already in the list and invalid entries are reported as skipped.
+    `,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile := viper.GetString("hosts-file")
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		return importAction(os.Stdout, hostsFile, args[0], format)
+	},
+}
+
+func init() {
+	hostsCmd.AddCommand(importCmd)
+
+	importCmd.Flags().String(
+		"format",
+		"",
+		fmt.Sprintf("format of the file: %s, %s, %s or %s", scan.FormatNmapXML, scan.FormatEtcHosts, scan.FormatCSV, scan.FormatKnownHosts),
+	)
+	importCmd.MarkFlagRequired("format")
+}
+
+// importAction adds the hosts of file to the hosts list, skipping those already in it.
+// The hosts file is updated in a single transaction, so concurrent runs are safe.
+func importAction(out io.Writer, hostsFile, file, format string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	parsed, skipped, err := scan.ParseHosts(f, format)
+	if err != nil {
+		return err
+	}
+
+	imported := 0
+
+	err = scan.UpdateHostsFile(hostsFile, func(hl *scan.HostsList) error {
+		for _, host := range parsed.Hosts {
+			if err := hl.Add(host); err != nil {
+				var hostErr *scan.HostError
+				if !errors.Is(err, scan.ErrExists) && !errors.Is(err, scan.ErrInvalidPattern) && !errors.Is(err, scan.ErrTooManyHosts) &&
+					!errors.As(err, &hostErr) {
+					return err
+				}
+
+				reason := "already in the list"
+				if !errors.Is(err, scan.ErrExists) {
+					reason = err.Error()
+				}
+
+				skipped = append(skipped, scan.SkippedEntry{Entry: host, Reason: reason})
+				continue
+			}
+
+			if err := hl.SetInfo(host, parsed.Info[host]); err != nil {
+				// A host is only worth adding together with its port overrides.
+				if err := hl.Remove(host); err != nil {
+					return err
+				}
+
+				skipped = append(skipped, scan.SkippedEntry{Entry: host, Reason: err.Error()})
+				continue
+			}
+
+			imported++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(out, "Imported %d hosts from %s\n", imported, file); err != nil {
+		return err
+	}
+
+	for _, s := range skipped {
+		if _, err := fmt.Fprintf(out, "Skipped %s\n", s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -32,6 +32,15 @@ var listCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostsFile := viper.GetString("hosts-file")
 
+		expand, err := cmd.Flags().GetBool("expand")
+		if err != nil {
+			return err
+		}
+
+		if expand {
+			return listExpandedAction(os.Stdout, hostsFile, args)
+		}
+
 		return listAction(os.Stdout, hostsFile, args)
 	},
 	Aliases: []string{"l"},
@@ -40,6 +49,8 @@ var listCmd = &cobra.Command{
 func init() {
 	hostsCmd.AddCommand(listCmd)
 
+	listCmd.Flags().BoolP("expand", "e", false, "show the concrete targets of CIDR blocks, ranges and patterns")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -67,3 +78,21 @@ func listAction(out io.Writer, hostsFile string, args []string) error {
 
 	return nil
 }
+
+// listExpandedAction runs when user calls pscan host list --expand,
+// it lists the targets which the scan command is going to scan.
+func listExpandedAction(out io.Writer, hostsFile string, args []string) error {
+	hl := &scan.HostsList{}
+
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	for _, h := range hl.Targets() {
+		if _, err := fmt.Fprintln(out, h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// notifyTimeout caps how long a single notification can take,
+// a hanging webhook or command must not stall the watch.
+const notifyTimeout = 30 * time.Second
+
+// notifier delivers the changes found by the watch command.
+type notifier interface {
+	notify(ctx context.Context, at time.Time, changes []scan.Change) error
+}
+
+// changeEvent is the JSON payload sent to the webhooks and the commands.
+type changeEvent struct {
+	Time    time.Time     `json:"time"`
+	Changes []scan.Change `json:"changes"`
+}
+
+// notifyAll hands the changes to every notifier. A failing notifier is reported on stderr
+// but does not stop the others, nor the watch.
+func notifyAll(ctx context.Context, notifiers []notifier, at time.Time, changes []scan.Change) {
+	for _, n := range notifiers {
+		if err := n.notify(ctx, at, changes); err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot notify the changes:", err)
+		}
+	}
+}
+
+// writerNotifier writes a line per change, e.g. to stdout or a log file.
+type writerNotifier struct {
+	out io.Writer
+}
+
+func (n *writerNotifier) notify(ctx context.Context, at time.Time, changes []scan.Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintf(n.out, "%s: %s\n", at.Format(time.RFC3339), c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webhookNotifier POSTs the changes as JSON to a URL.
+type webhookNotifier struct {
+	url string
+}
+
+func (n *webhookNotifier) notify(ctx context.Context, at time.Time, changes []scan.Change) error {
+	body, err := json.Marshal(changeEvent{Time: at, Changes: changes})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered with %s", n.url, resp.Status)
+	}
+
+	return nil
+}
+
+// execNotifier runs a shell command with the changes as JSON on its standard input.
+type execNotifier struct {
+	command string
+}
+
+func (n *execNotifier) notify(ctx context.Context, at time.Time, changes []scan.Change) error {
+	body, err := json.Marshal(changeEvent{Time: at, Changes: changes})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", n.command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command %q failed: %w", n.command, err)
+	}
+
+	return nil
+}
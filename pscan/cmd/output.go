@@ -0,0 +1,346 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// Supported output formats of the scan results.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputCSV  = "csv"
+	outputXML  = "xml"
+)
+
+var ErrInvalidOutput = errors.New("invalid output format")
+
+func validateOutput(format string) error {
+	switch format {
+	case outputText, outputJSON, outputCSV, outputXML:
+		return nil
+	default:
+		return fmt.Errorf("%w:%q, use one of text, json, csv or xml", ErrInvalidOutput, format)
+	}
+}
+
+// printResults writes the scan results to out in the output format of cfg.
+func printResults(out io.Writer, results []scan.Results, cfg scanConfig) error {
+	switch cfg.output {
+	case outputJSON:
+		return printJSON(out, results)
+	case outputCSV:
+		return printCSV(out, results)
+	case outputXML:
+		return printXML(out, results, cfg)
+	default:
+		return printText(out, results, cfg)
+	}
+}
+
+func printText(out io.Writer, results []scan.Results, cfg scanConfig) error {
+	message := ""
+
+	labels := hostLabels(results)
+
+	// The string concatnation here is not optimized for large results,
+	// it is just used for covering this basic CLI application.
+	for i, r := range results {
+		message += fmt.Sprintf("%s:", labels[i])
+
+		if r.NotFound {
+			message += " Host not found\n\n"
+			continue
+		}
+
+		if r.Status == scan.HostDown {
+			message += fmt.Sprintf(" Host is down (%s)\n\n", r.Reason)
+			continue
+		}
+
+		message += fmt.Sprintln()
+
+		for _, p := range r.PortStates {
+			message += fmt.Sprintf("\t%d: %s", p.Port, p.State)
+
+			// Show why the port is in its state and how long the answer took, like the other formats do.
+			if details := portDetails(p); details != "" {
+				message += fmt.Sprintf(" (%s)", details)
+			}
+
+			// Service and version are only detected when banner grabbing is enabled.
+			if p.Service != "" {
+				message += fmt.Sprintf(" %s", strings.TrimSpace(p.Service+" "+p.Version))
+			}
+
+			if p.Banner != "" {
+				message += fmt.Sprintf("\n\t\tbanner: %s", p.Banner)
+			}
+
+			if p.TLS != nil {
+				message += fmt.Sprintf("\n\t\ttls: %s %s", p.TLS.Version, p.TLS.CipherSuite)
+				message += fmt.Sprintf(
+					"\n\t\tcertificate: %s, issued by %s, expires %s",
+					p.TLS.Subject, p.TLS.Issuer, p.TLS.NotAfter.Format(time.DateOnly),
+				)
+
+				if p.TLS.VerifyError != "" {
+					message += fmt.Sprintf("\n\t\tuntrusted: %s", p.TLS.VerifyError)
+				}
+
+				if warning := expiryWarning(*p.TLS, cfg.tlsWarn); warning != "" {
+					message += fmt.Sprintf("\n\t\twarning: %s", warning)
+				}
+			}
+
+			if p.HTTP != nil {
+				message += fmt.Sprintf("\n\t\thttp: %s %s in %s", p.HTTP.URL, p.HTTP.StatusText(), p.HTTP.ResponseTime.Round(time.Millisecond))
+
+				if p.HTTP.Server != "" {
+					message += fmt.Sprintf("\n\t\tserver: %s", p.HTTP.Server)
+				}
+
+				if p.HTTP.Title != "" {
+					message += fmt.Sprintf("\n\t\ttitle: %s", p.HTTP.Title)
+				}
+
+				if len(p.HTTP.Redirects) > 0 {
+					message += fmt.Sprintf("\n\t\tredirects: %s", strings.Join(p.HTTP.Redirects, " -> "))
+				}
+			}
+
+			message += fmt.Sprintln()
+		}
+
+		message += fmt.Sprintln()
+	}
+
+	_, err := fmt.Fprint(out, message)
+	return err
+}
+
+// expiryWarning returns a warning when the certificate expires within the given time,
+// and an empty string otherwise.
+func expiryWarning(info scan.TLSInfo, within time.Duration) string {
+	now := time.Now()
+
+	switch {
+	case info.NotAfter.Before(now):
+		return fmt.Sprintf("the certificate expired on %s", info.NotAfter.Format(time.DateOnly))
+	case info.ExpiresWithin(within, now):
+		days := int(info.NotAfter.Sub(now).Hours() / 24)
+		return fmt.Sprintf("the certificate expires in %d days, on %s", days, info.NotAfter.Format(time.DateOnly))
+	default:
+		return ""
+	}
+}
+
+// printExpiryWarnings writes a line per certificate which expires within the given time.
+// The text output shows the warnings next to the ports, the other formats are meant for
+// machines, so the warnings go to a separate writer.
+func printExpiryWarnings(out io.Writer, results []scan.Results, within time.Duration) error {
+	labels := hostLabels(results)
+
+	for i, r := range results {
+		for _, p := range r.PortStates {
+			if p.TLS == nil {
+				continue
+			}
+
+			if warning := expiryWarning(*p.TLS, within); warning != "" {
+				if _, err := fmt.Fprintf(out, "Warning: %s:%d: %s\n", labels[i], p.Port, warning); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// portDetails joins the reason and the latency of a port, those which are known.
+func portDetails(p scan.PortState) string {
+	details := []string{}
+
+	if p.Reason != "" {
+		details = append(details, p.Reason)
+	}
+
+	if p.Latency > 0 {
+		details = append(details, roundLatency(p.Latency).String())
+	}
+
+	return strings.Join(details, ", ")
+}
+
+// roundLatency keeps the latency readable, e.g. 1.23ms instead of 1.234567ms.
+func roundLatency(d time.Duration) time.Duration {
+	if rounded := d.Round(10 * time.Microsecond); rounded > 0 {
+		return rounded
+	}
+
+	return d.Round(time.Microsecond)
+}
+
+// hostLabels names the results in the text outputs. Hosts scanned on every address
+// have a result per address, which are told apart by the address.
+func hostLabels(results []scan.Results) []string {
+	count := map[string]int{}
+	for _, r := range results {
+		count[r.Host]++
+	}
+
+	labels := make([]string, len(results))
+	for i, r := range results {
+		labels[i] = r.Host
+
+		if count[r.Host] > 1 {
+			labels[i] = fmt.Sprintf("%s (%s)", r.Host, r.Address)
+		}
+	}
+
+	return labels
+}
+
+func printJSON(out io.Writer, results []scan.Results) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(results)
+}
+
+func printCSV(out io.Writer, results []scan.Results) error {
+	w := csv.NewWriter(out)
+
+	if err := w.Write(scan.CSVHeader()); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if err := w.WriteAll(r.CSVRecords()); err != nil {
+			return err
+		}
+	}
+
+	return w.Error()
+}
+
+// nmapRun is the root element of Nmap's XML output, the hosts are marshalled by the scan package.
+type nmapRun struct {
+	XMLName          xml.Name       `xml:"nmaprun"`
+	Scanner          string         `xml:"scanner,attr"`
+	Version          string         `xml:"version,attr"`
+	XMLOutputVersion string         `xml:"xmloutputversion,attr"`
+	Start            int64          `xml:"start,attr"`
+	StartStr         string         `xml:"startstr,attr"`
+	ScanInfo         nmapScanInfo   `xml:"scaninfo"`
+	Hosts            []scan.Results `xml:"host"`
+	RunStats         nmapRunStats   `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time    int64  `xml:"time,attr"`
+	TimeStr string `xml:"timestr,attr"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+func printXML(out io.Writer, results []scan.Results, cfg scanConfig) error {
+	now := time.Now()
+
+	ports := make([]string, 0, len(cfg.ports))
+	for _, p := range cfg.ports {
+		ports = append(ports, strconv.Itoa(p))
+	}
+
+	scanType, protocol := scan.MethodConnect, scan.ProtocolTCP
+
+	switch {
+	case cfg.method == scan.MethodUDP, cfg.method == "" && cfg.opts.UDP:
+		scanType, protocol = scan.MethodUDP, scan.ProtocolUDP
+	case cfg.method != "":
+		scanType = cfg.method
+	}
+
+	run := nmapRun{
+		Scanner:          "pscan",
+		Version:          rootCmd.Version,
+		XMLOutputVersion: "1.05",
+		Start:            now.Unix(),
+		StartStr:         now.Format(time.ANSIC),
+		ScanInfo: nmapScanInfo{
+			Type:        scanType,
+			Protocol:    protocol,
+			NumServices: len(cfg.ports),
+			Services:    strings.Join(ports, ","),
+		},
+		Hosts: results,
+		RunStats: nmapRunStats{
+			Finished: nmapFinished{Time: now.Unix(), TimeStr: now.Format(time.ANSIC)},
+			Hosts:    nmapHostStats{Total: len(results)},
+		},
+	}
+
+	for _, r := range results {
+		if r.NotFound || r.Status == scan.HostDown {
+			run.RunStats.Hosts.Down++
+			continue
+		}
+
+		run.RunStats.Hosts.Up++
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(out)
+	return err
+}
@@ -0,0 +1,205 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrUnknownProfile = errors.New("unknown profile")
+	ErrInvalidSetting = errors.New("invalid setting")
+)
+
+// builtinProfiles are available without a config file, the profiles of the config file
+// replace them when they use the same name.
+var builtinProfiles = map[string]map[string]any{
+	"quick": {
+		"ports":   "top10",
+		"timeout": "500ms",
+	},
+	"full": {
+		"ports":   "all",
+		"retries": 1,
+	},
+	"web": {
+		"ports":  "http,https,http-alt,https-alt",
+		"banner": true,
+		"tls":    true,
+		"http":   true,
+	},
+}
+
+// profiles returns the built-in profiles merged with the ones under the profiles key of the config file.
+func profiles() map[string]map[string]any {
+	merged := map[string]map[string]any{}
+	for name, settings := range builtinProfiles {
+		merged[name] = settings
+	}
+
+	for name := range viper.GetStringMap("profiles") {
+		merged[name] = viper.GetStringMap("profiles." + name)
+	}
+
+	return merged
+}
+
+// profileNames returns the names of the profiles in alphabetical order.
+func profileNames() []string {
+	names := []string{}
+	for name := range profiles() {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// profileFlags are the flags which the profiles can set, the ones of the scan command.
+// The commands sharing the scan flags ignore the settings they do not have.
+var profileFlags *pflag.FlagSet
+
+// applyProfile merges the settings of the named profile over the config file,
+// so that the flags and the environment variables still take precedence over them.
+func applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	settings, ok := profiles()[name]
+	if !ok {
+		return fmt.Errorf("%w:%s, use one of %s", ErrUnknownProfile, name, strings.Join(profileNames(), ", "))
+	}
+
+	if err := validateSettings(settings, profileFlags); err != nil {
+		return fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	return viper.MergeConfigMap(settings)
+}
+
+// bindFlags binds the flags of cmd to the configuration keys of the same name,
+// so that every flag can be set in the config file or with a PSCAN_ environment variable as well.
+// The flags are shared by several commands, so they can only be bound to Viper
+// once it is known which command runs.
+func bindFlags(cmd *cobra.Command) error {
+	var err error
+
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if err == nil {
+			err = viper.BindPFlag(f.Name, f)
+		}
+	})
+
+	return err
+}
+
+// stringSlice returns the list of a configuration key, which the environment variables
+// give as a single comma separated value.
+func stringSlice(key string) []string {
+	values := []string{}
+
+	for _, v := range viper.GetStringSlice(key) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+
+	return values
+}
+
+// validateSettings checks that every setting is the value of a flag of known.
+// It reports all the invalid settings at once.
+func validateSettings(settings map[string]any, known *pflag.FlagSet) error {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	errs := []error{}
+	for _, key := range keys {
+		f := known.Lookup(key)
+		if f == nil {
+			errs = append(errs, fmt.Errorf("%w:%s is not a known setting", ErrInvalidSetting, key))
+			continue
+		}
+
+		if err := validateSetting(f, settings[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%w:%s: %w", ErrInvalidSetting, key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateSetting checks a single setting by the type of its flag, and the settings
+// which are parsed later on by their meaning.
+func validateSetting(f *pflag.Flag, value any) error {
+	text := fmt.Sprint(value)
+
+	var err error
+	switch f.Value.Type() {
+	case "int":
+		_, err = strconv.Atoi(text)
+	case "bool":
+		_, err = strconv.ParseBool(text)
+	case "duration":
+		_, err = time.ParseDuration(text)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	switch f.Name {
+	case "ports":
+		_, err = scan.ParsePorts(text)
+	case "discovery-ports":
+		if text != "" {
+			_, err = scan.ParsePorts(text)
+		}
+	case "output":
+		err = validateOutput(text)
+	case "method":
+		if text != "" {
+			_, err = scan.LookupProber(text)
+		}
+	case "addresses":
+		_, err = scan.ParseAddressMode(text)
+	case "fail-on":
+		_, err = scan.ParseSeverity(text)
+	case "profile":
+		if _, ok := profiles()[text]; !ok && text != "" {
+			err = fmt.Errorf("%w:%s", ErrUnknownProfile, text)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// progressRedraw is how often the progress bar is drawn at most.
+const progressRedraw = 100 * time.Millisecond
+
+// progressWidth is the number of cells of the progress bar.
+const progressWidth = 30
+
+// progressBar draws the progress of a scan on a single line of a terminal.
+// A nil progressBar draws nothing, it is used when the output is not a terminal or --quiet is set.
+type progressBar struct {
+	out   io.Writer
+	start time.Time
+	drawn time.Time
+}
+
+// newProgressBar returns a progress bar drawn to f, or nil when f is not a terminal.
+func newProgressBar(f *os.File) *progressBar {
+	if !isTerminal(f) {
+		return nil
+	}
+
+	return &progressBar{out: f, start: time.Now()}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// update redraws the bar, unless it was drawn recently and the scan is not done yet.
+func (b *progressBar) update(done, total int) {
+	if b == nil {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(b.drawn) < progressRedraw && done < total {
+		return
+	}
+
+	b.drawn = now
+	fmt.Fprintf(b.out, "\r\033[K%s", renderProgress(done, total, now.Sub(b.start)))
+}
+
+// clear erases the bar, so that the results can be printed on its line.
+func (b *progressBar) clear() {
+	if b == nil {
+		return
+	}
+
+	fmt.Fprint(b.out, "\r\033[K")
+}
+
+// renderProgress renders the bar for done out of total steps, with the time left estimated from the elapsed time.
+func renderProgress(done, total int, elapsed time.Duration) string {
+	// A scan without any step is done from the start.
+	percent := 100
+	if total > 0 {
+		percent = done * 100 / total
+	}
+
+	filled := percent * progressWidth / 100
+
+	bar := strings.Repeat("=", filled)
+	if filled < progressWidth {
+		bar += ">" + strings.Repeat(" ", progressWidth-filled-1)
+	}
+
+	line := fmt.Sprintf("[%s] %3d%% %d/%d", bar, percent, done, total)
+
+	if done > 0 && done < total {
+		eta := elapsed * time.Duration(total-done) / time.Duration(done)
+		line += fmt.Sprintf(" ETA %s", eta.Round(time.Second))
+	}
+
+	return line
+}
+
+// resultsStream prints the text and CSV results of the hosts as soon as they are scanned,
+// the other formats are printed once the scan is over.
+// The results keep the order of a complete scan, a host is held back until the hosts before it are printed.
+// The results of a host scanned on all of its addresses are printed together, so that they get the same labels.
+type resultsStream struct {
+	out io.Writer
+	cfg scanConfig
+	// pending holds the scanned hosts by their index until they can be printed.
+	pending map[int]scan.Results
+	// next is the index of the next result to print.
+	next   int
+	header bool
+	err    error
+}
+
+// newResultsStream returns a stream printing the results in the output format of cfg.
+func newResultsStream(out io.Writer, cfg scanConfig) *resultsStream {
+	return &resultsStream{out: out, cfg: cfg, pending: map[int]scan.Results{}}
+}
+
+// streams reports whether the output format can be printed host by host,
+// JSON and XML need the whole results.
+func (s *resultsStream) streams() bool {
+	return s.cfg.output != outputJSON && s.cfg.output != outputXML
+}
+
+// add records the results of the host at index i and prints the ones which are due.
+func (s *resultsStream) add(i int, r scan.Results) {
+	if !s.streams() {
+		return
+	}
+
+	s.pending[i] = r
+
+	for s.err == nil {
+		first, ok := s.pending[s.next]
+		if !ok {
+			return
+		}
+
+		group := []scan.Results{}
+		for j := s.next; j < s.next+s.groupSize(first); j++ {
+			r, ok := s.pending[j]
+			if !ok {
+				return
+			}
+
+			group = append(group, r)
+		}
+
+		for j := range group {
+			delete(s.pending, s.next+j)
+		}
+
+		s.next += len(group)
+		s.err = s.print(group)
+	}
+}
+
+// groupSize is the number of results of the host of r, one per address scanned.
+func (s *resultsStream) groupSize(r scan.Results) int {
+	if s.cfg.opts.AddressMode == scan.AddressAll && len(r.Addresses) > 1 {
+		return len(r.Addresses)
+	}
+
+	return 1
+}
+
+// finish prints the results which were not printed yet, the results of an interrupted scan included.
+// The hosts printed so far are the first ones of results, since a host is only printed after those before it.
+func (s *resultsStream) finish(results []scan.Results) error {
+	if !s.streams() {
+		return printResults(s.out, results, s.cfg)
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.print(results[min(s.next, len(results)):])
+}
+
+func (s *resultsStream) print(results []scan.Results) error {
+	if s.cfg.output != outputCSV {
+		return printText(s.out, results, s.cfg)
+	}
+
+	w := csv.NewWriter(s.out)
+
+	if !s.header {
+		s.header = true
+		if err := w.Write(scan.CSVHeader()); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range results {
+		if err := w.WriteAll(r.CSVRecords()); err != nil {
+			return err
+		}
+	}
+
+	return w.Error()
+}
@@ -69,6 +69,10 @@ func init() {
 
 	viper.BindPFlag("hosts-file", rootCmd.PersistentFlags().Lookup("hosts-file"))
 
+	// The scan history is kept next to the hosts file unless told otherwise.
+	rootCmd.PersistentFlags().String("history-file", "", "pScan history file (default is <hosts-file>.history)")
+	viper.BindPFlag("history-file", rootCmd.PersistentFlags().Lookup("history-file"))
+
 	versionTemplate := `{{printf "%s: %s - version %s\n" .Name .Short .Version}}`
 	rootCmd.SetVersionTemplate(versionTemplate)
 }
@@ -96,3 +100,13 @@ func initConfig() {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// historyFile returns the scan history file from the configuration,
+// falling back to a file next to the hosts file.
+func historyFile() string {
+	if f := viper.GetString("history-file"); f != "" {
+		return f
+	}
+
+	return viper.GetString("hosts-file") + ".history"
+}
@@ -16,9 +16,15 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/acikgozb/cli-playground/pscan/scan"
 	"github.com/spf13/cobra"
@@ -32,55 +38,268 @@ var scanCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostsFile := viper.GetString("hosts-file")
 
-		ports, err := cmd.Flags().GetIntSlice("ports")
+		cfg, err := scanConfigFromFlags(cmd)
 		if err != nil {
 			return err
 		}
 
-		return scanAction(os.Stdout, hostsFile, ports)
+		if !viper.GetBool("no-history") {
+			cfg.historyFile = historyFile()
+		}
+
+		cfg.quiet = viper.GetBool("quiet")
+		cfg.opts.Checkpoint = viper.GetString("checkpoint")
+		cfg.opts.CheckpointInterval = viper.GetDuration("checkpoint-interval")
+
+		// A resumed scan keeps saving its progress to the checkpoint it resumes from.
+		if resume := viper.GetString("resume"); resume != "" {
+			cp, err := scan.LoadCheckpoint(resume)
+			if err != nil {
+				return err
+			}
+
+			cfg.opts.Resume = cp.Results
+
+			if cfg.opts.Checkpoint == "" {
+				cfg.opts.Checkpoint = resume
+			}
+		}
+
+		// Cancel the scan on SIGINT or SIGTERM, so that the user still gets
+		// a report of the ports scanned until then.
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		return scanAction(ctx, os.Stdout, hostsFile, cfg)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(scanCmd)
 
-	scanCmd.Flags().IntSliceP("ports", "p", []int{22, 80, 443}, "ports to scan")
+	addScanFlags(scanCmd)
+	scanCmd.Flags().StringP("output", "o", outputText, "output format: text, json, csv or xml")
+	scanCmd.Flags().Bool("no-history", false, "do not save the results to the scan history")
+	scanCmd.Flags().BoolP("quiet", "q", false, "only print the results, without the progress bar and the warnings")
+	scanCmd.Flags().String("checkpoint", "", "save the progress of the scan to this file, so that it can be resumed")
+	scanCmd.Flags().Duration("checkpoint-interval", scan.DefaultCheckpointInterval, "how often the checkpoint is saved")
+	scanCmd.Flags().String("resume", "", "resume the scan saved to this checkpoint file, skipping the hosts and ports it holds")
+
+	profileFlags = scanCmd.LocalFlags()
+}
+
+// addScanFlags defines the flags which tune how the hosts are scanned,
+// they are shared by the commands running scans.
+func addScanFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(
+		"ports",
+		"p",
+		"22,80,443",
+		"ports to scan, e.g. 1-1024,!25 or ssh,http,https or top100",
+	)
+	cmd.Flags().IntP("workers", "w", scan.DefaultWorkers, "number of concurrent scan workers")
+	cmd.Flags().BoolP("udp", "u", false, "scan UDP ports instead of TCP ports")
+	cmd.Flags().BoolP("banner", "b", false, "grab banners of open TCP ports to detect their services")
+	cmd.Flags().StringP(
+		"method",
+		"m",
+		"",
+		fmt.Sprintf("scan method (%s), --udp and --banner are shortcuts for it", strings.Join(scan.Methods(), ", ")),
+	)
+	cmd.Flags().Bool("tls", false, "inspect the TLS certificates of open TCP ports")
+	cmd.Flags().Int("tls-warn-days", 30, "warn about TLS certificates expiring within this many days")
+	cmd.Flags().Bool("http", false, "request the root page of open TCP ports to record their status, server, title and redirects")
+	cmd.Flags().StringSliceP("group", "g", nil, "only scan the hosts in any of these groups")
+	cmd.Flags().StringSliceP("tag", "t", nil, "only scan the hosts with any of these tags")
+	cmd.Flags().String("profile", "", "scan profile from the config file or a built-in one: quick, full or web")
+
+	// Politeness controls, they can be set in the config file or the environment as well.
+	cmd.Flags().Duration("timeout", scan.DefaultTimeout, "how long a probe waits for an answer")
+	cmd.Flags().Int("retries", 0, "how many times to retry the probes of filtered ports")
+	cmd.Flags().Int("rate", 0, "maximum number of probes per second, 0 means unlimited")
+	cmd.Flags().Int("max-per-host", 0, "maximum number of concurrent probes per host, 0 means unlimited")
+	cmd.Flags().Bool("randomize", false, "scan the hosts and ports in a random order")
+	cmd.Flags().Duration("jitter", 0, "maximum random delay added before every probe")
+
+	cmd.Flags().Bool("no-discovery", false, "scan every host without checking whether it is up first")
+	addDiscoveryFlags(cmd)
+	addAddressFlags(cmd)
+}
+
+// addAddressFlags defines the flags which select the addresses of the hosts to scan.
+func addAddressFlags(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"addresses",
+		scan.AddressIPv4.String(),
+		"addresses of the hosts to scan: ipv4 or ipv6 for the first address of that family, or all",
+	)
+}
+
+// addressMode returns the address mode of the addresses setting.
+func addressMode() (scan.AddressMode, error) {
+	return scan.ParseAddressMode(viper.GetString("addresses"))
+}
+
+// scanConfigFromFlags collects the flags defined by addScanFlags into a scan configuration.
+// Every flag can also be set by the selected profile, the config file or a PSCAN_ environment variable,
+// see bindFlags and applyProfile.
+func scanConfigFromFlags(cmd *cobra.Command) (scanConfig, error) {
+	cfg := scanConfig{}
+
+	if err := bindFlags(cmd); err != nil {
+		return cfg, err
+	}
+
+	if err := applyProfile(viper.GetString("profile")); err != nil {
+		return cfg, err
+	}
+
+	var err error
+	if cfg.ports, err = scan.ParsePorts(viper.GetString("ports")); err != nil {
+		return cfg, err
+	}
+
+	cfg.opts.Workers = viper.GetInt("workers")
+	cfg.opts.UDP = viper.GetBool("udp")
+	cfg.opts.Banner = viper.GetBool("banner")
+	cfg.opts.TLS = viper.GetBool("tls")
+	cfg.opts.HTTP = viper.GetBool("http")
+	cfg.tlsWarn = time.Duration(viper.GetInt("tls-warn-days")) * 24 * time.Hour
+
+	if cfg.method = viper.GetString("method"); cfg.method != "" {
+		if cfg.opts.Prober, err = scan.LookupProber(cfg.method); err != nil {
+			return cfg, err
+		}
+	}
+
+	cfg.groups = stringSlice("group")
+	cfg.tags = stringSlice("tag")
+	cfg.opts.NoDiscovery = viper.GetBool("no-discovery")
+
+	if cfg.opts.DiscoveryPorts, err = discoveryPorts(); err != nil {
+		return cfg, err
+	}
+
+	if cfg.opts.AddressMode, err = addressMode(); err != nil {
+		return cfg, err
+	}
+
+	politeness(&cfg.opts)
+
+	// Only the commands printing the results have an output format.
+	if cmd.Flags().Lookup("output") != nil {
+		cfg.output = viper.GetString("output")
+	}
+
+	return cfg, nil
+}
+
+// politeness sets the politeness options from the configuration.
+func politeness(opts *scan.Options) {
+	opts.Timeout = viper.GetDuration("timeout")
+	opts.Retries = viper.GetInt("retries")
+	opts.Rate = viper.GetInt("rate")
+	opts.MaxPerHost = viper.GetInt("max-per-host")
+	opts.Randomize = viper.GetBool("randomize")
+	opts.Jitter = viper.GetDuration("jitter")
+}
+
+// scanConfig groups the settings of a scan run which are collected from the flags.
+type scanConfig struct {
+	ports []int
+	opts  scan.Options
+	// method is the name of the scan method set on opts, empty when it follows opts.UDP.
+	method string
+	output string
+	// tlsWarn is how soon a certificate has to expire to get a warning.
+	tlsWarn time.Duration
+	// groups and tags select the hosts to scan, see scan.HostsList.Select.
+	groups []string
+	tags   []string
+	// historyFile is where the results are recorded, no history is kept when it is empty.
+	historyFile string
+	// quiet only prints the results, without the progress bar and the warnings on stderr.
+	quiet bool
 }
 
 // scanAction ties Cobra with our scan package.
-func scanAction(out io.Writer, hostsFile string, ports []int) error {
+// If ctx ends before the scan is complete, the partial results are printed
+// before the interruption is reported.
+func scanAction(ctx context.Context, out io.Writer, hostsFile string, cfg scanConfig) error {
+	// Validate the output format upfront, a typo should not cost a whole scan.
+	if err := validateOutput(cfg.output); err != nil {
+		return err
+	}
+
 	hl := &scan.HostsList{}
 
 	if err := hl.Load(hostsFile); err != nil {
 		return err
 	}
 
-	results := scan.Run(hl, ports)
-	return printResults(out, results)
-}
+	hl = hl.Select(cfg.groups, cfg.tags)
 
-func printResults(out io.Writer, results []scan.Results) error {
-	message := ""
+	// A checkpoint which cannot be saved should fail the scan now, not when it gets interrupted.
+	if cfg.opts.Checkpoint != "" {
+		cp := scan.Checkpoint{Time: time.Now(), Results: cfg.opts.Resume}
+		if err := scan.SaveCheckpoint(cfg.opts.Checkpoint, cp); err != nil {
+			return fmt.Errorf("cannot save the checkpoint: %w", err)
+		}
+	}
+
+	// The text and CSV results are printed as the hosts are scanned, while the progress goes to stderr.
+	// The bar is cleared before printing, in case both end up on the same terminal.
+	var bar *progressBar
+	if !cfg.quiet {
+		bar = newProgressBar(os.Stderr)
+	}
 
-	// The string concatnation here is not optimized for large results,
-	// it is just used for covering this basic CLI application.
-	for _, r := range results {
-		message += fmt.Sprintf("%s:", r.Host)
+	stream := newResultsStream(out, cfg)
 
-		if r.NotFound {
-			message += " Host not found\n\n"
-			continue
+	opts := cfg.opts
+	opts.Progress = func(p scan.Progress) {
+		if p.Results != nil {
+			bar.clear()
+			stream.add(p.Index, *p.Results)
 		}
 
-		message += fmt.Sprintln()
+		bar.update(p.Done, p.Total)
+	}
+
+	results, scanErr := scan.RunContext(ctx, hl, cfg.ports, opts)
+	bar.clear()
+
+	if err := stream.finish(results); err != nil {
+		return err
+	}
+
+	if cfg.output != outputText && !cfg.quiet {
+		if err := printExpiryWarnings(os.Stderr, results, cfg.tlsWarn); err != nil {
+			return err
+		}
+	}
+
+	if cfg.historyFile != "" {
+		rec := scan.Record{Time: time.Now(), Partial: scanErr != nil, Results: results}
+		if _, err := scan.AppendHistory(cfg.historyFile, rec); err != nil {
+			return fmt.Errorf("cannot save the scan to the history: %w", err)
+		}
+	}
 
-		for _, p := range r.PortStates {
-			message += fmt.Sprintf("\t%d: %s\n", p.Port, p.Open)
+	if scanErr != nil {
+		if cfg.opts.Checkpoint != "" {
+			return fmt.Errorf("scan interrupted, the results are partial, resume it with --resume %s: %w", cfg.opts.Checkpoint, scanErr)
 		}
 
-		message += fmt.Sprintln()
+		return fmt.Errorf("scan interrupted, the results are partial: %w", scanErr)
+	}
+
+	// A complete scan has nothing left to resume.
+	if cfg.opts.Checkpoint != "" {
+		if err := os.Remove(cfg.opts.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
 	}
 
-	_, err := fmt.Fprint(out, message)
-	return err
+	return nil
 }
@@ -0,0 +1,447 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ErrInvalidRequest is returned for request bodies which are not the expected JSON.
+var ErrInvalidRequest = errors.New("invalid request body")
+
+// shutdownTimeout is how long the server waits for the pending requests when it stops.
+const shutdownTimeout = 5 * time.Second
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API to manage the hosts and run scans",
+	Long: `Serves a JSON REST API over HTTP, so that other services can manage the hosts list and run scans.
+    The API has no authentication, it only listens on localhost unless --addr says otherwise.
+
+    GET    /hosts                list the hosts with their groups, tags and ports
+    POST   /hosts                add a host: {"host": "example.com", "groups": ["web"], "tags": [], "ports": "80,443"}
+    DELETE /hosts/<host>         remove a host
+    POST   /scans                queue a scan: {"ports": "1-1024", "groups": [], "tags": [], "banner": true, "tls": true, "http": true}
+    GET    /scans                list the scan jobs
+    GET    /scans/<id>           get the status and the progress of a scan job
+    GET    /scans/<id>/results   get the results of a finished scan job
+    DELETE /scans/<id>           cancel a scan job, a running one keeps its partial results
+
+    The scan flags set the defaults of the scan jobs, a request only overrides the settings it holds.
+    Jobs are run in the order they are queued, --jobs at a time, and only kept in memory:
+    the last 100 finished jobs can be queried until the server stops.
+    `,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile := viper.GetString("hosts-file")
+
+		scanCfg, err := scanConfigFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !viper.GetBool("no-history") {
+			scanCfg.historyFile = historyFile()
+		}
+
+		cfg := serveConfig{scanConfig: scanCfg}
+
+		if cfg.addr, err = cmd.Flags().GetString("addr"); err != nil {
+			return err
+		}
+
+		if cfg.jobs, err = cmd.Flags().GetInt("jobs"); err != nil {
+			return err
+		}
+
+		if cfg.queueSize, err = cmd.Flags().GetInt("queue-size"); err != nil {
+			return err
+		}
+
+		// Stop serving on SIGINT or SIGTERM, the running jobs get canceled.
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		return serveAction(ctx, os.Stdout, hostsFile, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	addScanFlags(serveCmd)
+	serveCmd.Flags().String("addr", "localhost:8080", "address to listen on, the API has no authentication so keep it local")
+	serveCmd.Flags().Int("jobs", 1, "number of scan jobs running at the same time")
+	serveCmd.Flags().Int("queue-size", 100, "maximum number of scan jobs waiting for their turn")
+	serveCmd.Flags().Bool("no-history", false, "do not save the results of the scan jobs to the scan history")
+}
+
+// serveConfig groups the settings of the serve command.
+type serveConfig struct {
+	// scanConfig holds the defaults of the scan jobs.
+	scanConfig
+	addr      string
+	jobs      int
+	queueSize int
+}
+
+// serveAction serves the API on cfg.addr until ctx ends.
+func serveAction(ctx context.Context, out io.Writer, hostsFile string, cfg serveConfig) error {
+	ln, err := net.Listen("tcp", cfg.addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	// The jobs have to be canceled before waiting for them, whichever way the server stops.
+	s := newServer(hostsFile, cfg)
+	wait := s.jobs.start(ctx, cfg.jobs)
+	defer wait()
+	defer cancel()
+
+	srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	fmt.Fprintln(out, "Serving the API on", ln.Addr())
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	return srv.Shutdown(shutdownCtx)
+}
+
+// server implements the REST API on a hosts file.
+type server struct {
+	hostsFile string
+	// cfg holds the defaults of the scan jobs.
+	cfg  scanConfig
+	jobs *jobQueue
+}
+
+func newServer(hostsFile string, cfg serveConfig) *server {
+	s := &server{hostsFile: hostsFile, cfg: cfg.scanConfig}
+	s.jobs = newJobQueue(cfg.queueSize, s.runScan)
+
+	return s
+}
+
+// handler routes the requests to the endpoints of the API.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/hosts", s.handleHosts)
+	mux.HandleFunc("/hosts/", s.handleHost)
+	mux.HandleFunc("/scans", s.handleScans)
+	mux.HandleFunc("/scans/", s.handleScan)
+
+	return mux
+}
+
+// hostEntry is a host of the list with its info, as the API reads and writes it.
+type hostEntry struct {
+	Host string `json:"host"`
+	scan.HostInfo
+}
+
+func (s *server) handleHosts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		hl := &scan.HostsList{}
+		if err := hl.Load(s.hostsFile); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		hosts := make([]hostEntry, 0, len(hl.Hosts))
+		for _, h := range hl.Hosts {
+			hosts = append(hosts, hostEntry{Host: h, HostInfo: hl.Info[h]})
+		}
+
+		writeJSON(w, http.StatusOK, hosts)
+	case http.MethodPost:
+		entry := hostEntry{}
+		if err := decodeJSON(r, &entry); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		// The same transaction as the add command, see addAction.
+		err := scan.UpdateHostsFile(s.hostsFile, func(hl *scan.HostsList) error {
+			host, err := scan.NormalizeHost(entry.Host)
+			if err != nil {
+				return err
+			}
+
+			if err := hl.Add(host); err != nil {
+				return err
+			}
+
+			entry.Host = host
+			return hl.SetInfo(host, entry.HostInfo)
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, entry)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *server) handleHost(w http.ResponseWriter, r *http.Request) {
+	// Networks have a slash in them, the host is the rest of the path.
+	host := strings.TrimPrefix(r.URL.Path, "/hosts/")
+
+	if r.Method != http.MethodDelete {
+		methodNotAllowed(w, http.MethodDelete)
+		return
+	}
+
+	err := scan.UpdateHostsFile(s.hostsFile, func(hl *scan.HostsList) error {
+		return hl.Remove(host)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scanRequest is the body of a scan job request, the settings it leaves out keep the defaults of the server.
+type scanRequest struct {
+	Ports  string   `json:"ports,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Banner *bool    `json:"banner,omitempty"`
+	TLS    *bool    `json:"tls,omitempty"`
+	HTTP   *bool    `json:"http,omitempty"`
+}
+
+// apply returns cfg with the settings of the request.
+func (req scanRequest) apply(cfg scanConfig) (scanConfig, error) {
+	if req.Ports != "" {
+		ports, err := scan.ParsePorts(req.Ports)
+		if err != nil {
+			return cfg, err
+		}
+
+		cfg.ports = ports
+	}
+
+	if req.Groups != nil {
+		cfg.groups = req.Groups
+	}
+
+	if req.Tags != nil {
+		cfg.tags = req.Tags
+	}
+
+	if req.Banner != nil {
+		cfg.opts.Banner = *req.Banner
+	}
+
+	if req.TLS != nil {
+		cfg.opts.TLS = *req.TLS
+	}
+
+	if req.HTTP != nil {
+		cfg.opts.HTTP = *req.HTTP
+	}
+
+	return cfg, nil
+}
+
+func (s *server) handleScans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.jobs.list())
+	case http.MethodPost:
+		req := scanRequest{}
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		cfg, err := req.apply(s.cfg)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		job, err := s.jobs.submit(req, cfg)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", "/scans/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *server) handleScan(w http.ResponseWriter, r *http.Request) {
+	id, results := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/scans/"), "/results")
+
+	switch {
+	case results && r.Method == http.MethodGet:
+		job, err := s.jobs.get(id)
+		if err == nil && !job.Status.finished() {
+			err = fmt.Errorf("%w:%s", ErrJobRunning, id)
+		}
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, job.results)
+	case results:
+		methodNotAllowed(w, http.MethodGet)
+	case r.Method == http.MethodGet:
+		job, err := s.jobs.get(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, job)
+	case r.Method == http.MethodDelete:
+		job, err := s.jobs.cancel(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, job)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// runScan runs the scan of a job like the scan command does, the hosts file is loaded when the job starts.
+func (s *server) runScan(ctx context.Context, cfg scanConfig, progress func(scan.Progress)) ([]scan.Results, error) {
+	hl := &scan.HostsList{}
+	if err := hl.Load(s.hostsFile); err != nil {
+		return nil, err
+	}
+
+	opts := cfg.opts
+	opts.Progress = progress
+
+	results, scanErr := scan.RunContext(ctx, hl.Select(cfg.groups, cfg.tags), cfg.ports, opts)
+
+	if cfg.historyFile != "" {
+		rec := scan.Record{Time: time.Now(), Partial: scanErr != nil, Results: results}
+		if _, err := scan.AppendHistory(cfg.historyFile, rec); err != nil {
+			return results, errors.Join(scanErr, fmt.Errorf("cannot save the scan to the history: %w", err))
+		}
+	}
+
+	return results, scanErr
+}
+
+// apiError is the body of the error responses.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// errorStatus maps the errors of the hosts list, the scan package and the job queue to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrJobNotFound), errors.Is(err, scan.ErrNotExists):
+		return http.StatusNotFound
+	case errors.Is(err, scan.ErrExists), errors.Is(err, ErrJobFinished), errors.Is(err, ErrJobRunning):
+		return http.StatusConflict
+	case errors.Is(err, ErrQueueFull):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, scan.ErrInvalidHost),
+		errors.Is(err, scan.ErrInvalidAddress),
+		errors.Is(err, scan.ErrInvalidHostName),
+		errors.Is(err, scan.ErrInvalidPort),
+		errors.Is(err, scan.ErrInvalidPortSpec),
+		errors.Is(err, scan.ErrUnknownService),
+		errors.Is(err, scan.ErrInvalidPattern),
+		errors.Is(err, scan.ErrTooManyHosts),
+		errors.Is(err, ErrInvalidRequest):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeJSON decodes the body of r into v, rejecting unknown fields so that typos do not go unnoticed.
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	// The status is sent already, a failing client cannot be told anymore.
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, errorStatus(err), apiError{Error: err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: http.StatusText(http.StatusMethodNotAllowed)})
+}
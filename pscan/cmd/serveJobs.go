@@ -0,0 +1,272 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+var (
+	ErrJobNotFound = errors.New("scan job not found")
+	ErrJobFinished = errors.New("scan job already finished")
+	ErrJobRunning  = errors.New("scan job not finished yet")
+	ErrQueueFull   = errors.New("scan job queue is full")
+)
+
+// keptJobs is the number of finished jobs kept for their results, the older ones are dropped.
+const keptJobs = 100
+
+// jobStatus is the stage of a scan job.
+type jobStatus string
+
+const (
+	jobQueued   jobStatus = "queued"
+	jobRunning  jobStatus = "running"
+	jobDone     jobStatus = "done"
+	jobFailed   jobStatus = "failed"
+	jobCanceled jobStatus = "canceled"
+)
+
+// finished reports whether the job will not change anymore.
+func (s jobStatus) finished() bool {
+	return s == jobDone || s == jobFailed || s == jobCanceled
+}
+
+// scanJob is a scan run by the job queue of the server.
+// Its exported fields are the status reported by the API, the results are fetched separately.
+type scanJob struct {
+	ID       string      `json:"id"`
+	Status   jobStatus   `json:"status"`
+	Request  scanRequest `json:"request"`
+	Created  time.Time   `json:"created"`
+	Started  *time.Time  `json:"started,omitempty"`
+	Finished *time.Time  `json:"finished,omitempty"`
+	// Done and Total count the steps of the scan, see scan.Progress.
+	Done  int `json:"done"`
+	Total int `json:"total"`
+	// Error tells why the job failed or got canceled.
+	Error string `json:"error,omitempty"`
+
+	cfg     scanConfig
+	results []scan.Results
+	// cancel stops the job once it runs.
+	cancel context.CancelFunc
+}
+
+// jobRunner runs the scan of a job, reporting its progress as it goes.
+type jobRunner func(ctx context.Context, cfg scanConfig, progress func(scan.Progress)) ([]scan.Results, error)
+
+// jobQueue runs the scan jobs in the order they were submitted, a few at a time.
+// The jobs are kept in memory only, they are lost when the server stops.
+type jobQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*scanJob
+	// order holds the IDs of the jobs in the order they were submitted.
+	order   []string
+	lastID  int
+	pending chan *scanJob
+	run     jobRunner
+}
+
+// newJobQueue returns a queue holding up to size jobs waiting for their turn.
+func newJobQueue(size int, run jobRunner) *jobQueue {
+	return &jobQueue{jobs: map[string]*scanJob{}, pending: make(chan *scanJob, max(size, 1)), run: run}
+}
+
+// start runs the queued jobs on the given number of workers until ctx ends.
+// The jobs get canceled with ctx, the returned function waits for the workers to return.
+func (q *jobQueue) start(ctx context.Context, workers int) (wait func()) {
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < max(workers, 1); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					q.cancelQueued(ctx.Err())
+					return
+				case job := <-q.pending:
+					q.runJob(ctx, job)
+				}
+			}
+		}()
+	}
+
+	return wg.Wait
+}
+
+// submit queues a scan job with the settings of cfg.
+func (q *jobQueue) submit(req scanRequest, cfg scanConfig) (scanJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := &scanJob{ID: strconv.Itoa(q.lastID + 1), Status: jobQueued, Request: req, Created: time.Now(), cfg: cfg}
+
+	select {
+	case q.pending <- job:
+	default:
+		return scanJob{}, ErrQueueFull
+	}
+
+	q.lastID++
+	q.jobs[job.ID] = job
+	q.order = append(q.order, job.ID)
+
+	return *job, nil
+}
+
+// get returns a copy of the job, its results included.
+func (q *jobQueue) get(id string) (scanJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return scanJob{}, fmt.Errorf("%w:%s", ErrJobNotFound, id)
+	}
+
+	return *job, nil
+}
+
+// list returns copies of the jobs in the order they were submitted.
+func (q *jobQueue) list() []scanJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]scanJob, 0, len(q.order))
+	for _, id := range q.order {
+		jobs = append(jobs, *q.jobs[id])
+	}
+
+	return jobs
+}
+
+// cancel stops a queued or running job. A running job keeps the results of the ports scanned until then.
+func (q *jobQueue) cancel(id string) (scanJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return scanJob{}, fmt.Errorf("%w:%s", ErrJobNotFound, id)
+	}
+
+	switch {
+	case job.Status.finished():
+		return *job, fmt.Errorf("%w:%s", ErrJobFinished, id)
+	case job.Status == jobQueued:
+		// The worker skips it when its turn comes.
+		q.finish(job, jobCanceled, context.Canceled)
+	default:
+		job.cancel()
+	}
+
+	return *job, nil
+}
+
+// cancelQueued cancels the jobs still waiting for their turn when the queue stops.
+func (q *jobQueue) cancelQueued(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, job := range q.jobs {
+		if job.Status == jobQueued {
+			q.finish(job, jobCanceled, err)
+		}
+	}
+}
+
+func (q *jobQueue) runJob(ctx context.Context, job *scanJob) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	q.mu.Lock()
+
+	if job.Status != jobQueued {
+		q.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	job.Status, job.Started, job.cancel = jobRunning, &now, cancel
+	cfg := job.cfg
+
+	q.mu.Unlock()
+
+	results, err := q.run(ctx, cfg, func(p scan.Progress) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		job.Done, job.Total = p.Done, p.Total
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// An interrupted scan still has the results of the ports scanned until then.
+	job.results = results
+
+	switch {
+	case err == nil:
+		q.finish(job, jobDone, nil)
+	case ctx.Err() != nil:
+		q.finish(job, jobCanceled, err)
+	default:
+		q.finish(job, jobFailed, err)
+	}
+}
+
+// finish records the end of a job and drops the oldest finished jobs beyond keptJobs.
+// It is called with q.mu held.
+func (q *jobQueue) finish(job *scanJob, status jobStatus, err error) {
+	now := time.Now()
+	job.Status, job.Finished = status, &now
+
+	if err != nil {
+		job.Error = err.Error()
+	}
+
+	finished := 0
+	for _, id := range q.order {
+		if q.jobs[id].Status.finished() {
+			finished++
+		}
+	}
+
+	order := q.order[:0]
+	for _, id := range q.order {
+		if finished > keptJobs && q.jobs[id].Status.finished() {
+			finished--
+			delete(q.jobs, id)
+			continue
+		}
+
+		order = append(order, id)
+	}
+
+	q.order = order
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// request sends a JSON request to the test server and decodes the response into v, when v is not nil.
+func request(t *testing.T, srv *httptest.Server, method, path, body string, v any) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("cannot decode the response of %s %s: %s\n", method, path, err)
+		}
+	}
+
+	return resp
+}
+
+func TestServeHosts(t *testing.T) {
+	tf, cleanup := setup(t, nil, false)
+	defer cleanup()
+
+	srv := httptest.NewServer(newServer(tf, serveConfig{}).handler())
+	defer srv.Close()
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Add", http.MethodPost, "/hosts", `{"host": "LocalHost.", "groups": ["web"]}`, http.StatusCreated},
+		{"AddNetwork", http.MethodPost, "/hosts", `{"host": "10.0.0.0/30"}`, http.StatusCreated},
+		{"AddExisting", http.MethodPost, "/hosts", `{"host": "localhost"}`, http.StatusConflict},
+		{"AddInvalid", http.MethodPost, "/hosts", `{"host": "local host"}`, http.StatusBadRequest},
+		{"AddInvalidPorts", http.MethodPost, "/hosts", `{"host": "host1", "ports": "http,nope"}`, http.StatusBadRequest},
+		{"AddUnknownField", http.MethodPost, "/hosts", `{"name": "host1"}`, http.StatusBadRequest},
+		{"RemoveNetwork", http.MethodDelete, "/hosts/10.0.0.0/30", "", http.StatusNoContent},
+		{"RemoveMissing", http.MethodDelete, "/hosts/host1", "", http.StatusNotFound},
+		{"MethodNotAllowed", http.MethodPut, "/hosts", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := request(t, srv, tc.method, tc.path, tc.body, nil)
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d instead\n", tc.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	hosts := []hostEntry{}
+	if resp := request(t, srv, http.MethodGet, "/hosts", "", &hosts); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d instead\n", http.StatusOK, resp.StatusCode)
+	}
+
+	expected := fmt.Sprint([]hostEntry{{Host: "localhost", HostInfo: scan.HostInfo{Groups: []string{"web"}}}})
+	if fmt.Sprint(hosts) != expected {
+		t.Errorf("expected hosts %s, got %v instead\n", expected, hosts)
+	}
+}
+
+func TestServeScans(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := newServer(tf, serveConfig{queueSize: 10})
+	wait := s.jobs.start(ctx, 1)
+	defer wait()
+	defer cancel()
+
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	if resp := request(t, srv, http.MethodPost, "/scans", `{"ports": "nope"}`, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d for invalid ports, got %d instead\n", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	job := scanJob{}
+
+	resp := request(t, srv, http.MethodPost, "/scans", fmt.Sprintf(`{"ports": "%d"}`, port), &job)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d instead\n", http.StatusAccepted, resp.StatusCode)
+	}
+
+	location := resp.Header.Get("Location")
+	if location != "/scans/"+job.ID {
+		t.Errorf("expected location %q, got %q instead\n", "/scans/"+job.ID, location)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); !job.Status.finished(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the job to finish, got status %q instead\n", job.Status)
+		}
+
+		request(t, srv, http.MethodGet, location, "", &job)
+	}
+
+	if job.Status != jobDone || job.Done != job.Total || job.Total != 2 {
+		t.Errorf("expected a done job with 2/2 steps, got %+v instead\n", job)
+	}
+
+	results := []scan.Results{}
+	if resp := request(t, srv, http.MethodGet, location+"/results", "", &results); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d instead\n", http.StatusOK, resp.StatusCode)
+	}
+
+	if len(results) != 1 || len(results[0].PortStates) != 1 || results[0].PortStates[0].State != scan.StateOpen {
+		t.Errorf("expected port %d of localhost to be open, got %v instead\n", port, results)
+	}
+
+	jobs := []scanJob{}
+	if request(t, srv, http.MethodGet, "/scans", "", &jobs); len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("expected job %s to be listed, got %v instead\n", job.ID, jobs)
+	}
+
+	if resp := request(t, srv, http.MethodDelete, location, "", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected status %d when canceling a finished job, got %d instead\n", http.StatusConflict, resp.StatusCode)
+	}
+
+	if resp := request(t, srv, http.MethodGet, "/scans/42", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d for a missing job, got %d instead\n", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	started := make(chan struct{})
+
+	// The scans run until they get canceled, with the results of a single host.
+	run := func(ctx context.Context, cfg scanConfig, progress func(scan.Progress)) ([]scan.Results, error) {
+		progress(scan.Progress{Done: 1, Total: 2})
+		close(started)
+
+		<-ctx.Done()
+		return []scan.Results{{Host: "host1"}}, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q := newJobQueue(1, run)
+	wait := q.start(ctx, 1)
+	defer wait()
+	defer cancel()
+
+	running, err := q.submit(scanRequest{}, scanConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-started
+
+	queued, err := q.submit(scanRequest{}, scanConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.submit(scanRequest{}, scanConfig{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected error %q, got %q instead\n", ErrQueueFull, err)
+	}
+
+	if job, err := q.get(running.ID); err != nil || job.Status != jobRunning || job.Done != 1 || job.Total != 2 {
+		t.Errorf("expected job %s to be running with 1/2 steps, got %+v, %v instead\n", running.ID, job, err)
+	}
+
+	// A queued job never runs once canceled.
+	if job, err := q.cancel(queued.ID); err != nil || job.Status != jobCanceled {
+		t.Errorf("expected job %s to be canceled, got %+v, %v instead\n", queued.ID, job, err)
+	}
+
+	if _, err := q.cancel(running.ID); err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	job := scanJob{}
+	for deadline := time.Now().Add(5 * time.Second); !job.Status.finished(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected job %s to finish, got status %q instead\n", running.ID, job.Status)
+		}
+
+		job, _ = q.get(running.ID)
+	}
+
+	// A canceled scan keeps its partial results.
+	if job.Status != jobCanceled || len(job.results) != 1 {
+		t.Errorf("expected job %s to be canceled with partial results, got %+v instead\n", running.ID, job)
+	}
+
+	if _, err := q.get("42"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected error %q, got %q instead\n", ErrJobNotFound, err)
+	}
+}
+
+func TestServeAction(t *testing.T) {
+	tf, cleanup := setup(t, nil, false)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Stop the server as soon as it serves.
+	out := writerFunc(func(p []byte) (int, error) {
+		cancel()
+		return len(p), nil
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveAction(ctx, out, tf, serveConfig{addr: "localhost:0", jobs: 1, queueSize: 1})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %q instead\n", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the server to stop once ctx ends")
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Scan the hosts repeatedly and report the changes",
+	Long: `Scans the hosts on every interval and compares the results with the previous scan.
+    Hosts which appear or disappear and ports which open or close are reported
+    to stdout, and optionally to a log file, a webhook or a command.
+
+    The webhook receives the changes as a JSON POST request, the command gets
+    the same JSON on its standard input.
+    `,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostsFile := viper.GetString("hosts-file")
+
+		scanCfg, err := scanConfigFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		cfg := watchConfig{scanConfig: scanCfg, notifiers: []notifier{&writerNotifier{out: os.Stdout}}}
+
+		if cfg.interval, err = cmd.Flags().GetDuration("interval"); err != nil {
+			return err
+		}
+
+		if cfg.count, err = cmd.Flags().GetInt("count"); err != nil {
+			return err
+		}
+
+		logFile, err := cmd.Flags().GetString("log-file")
+		if err != nil {
+			return err
+		}
+
+		if logFile != "" {
+			f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+
+			defer f.Close()
+			cfg.notifiers = append(cfg.notifiers, &writerNotifier{out: f})
+		}
+
+		webhook, err := cmd.Flags().GetString("webhook")
+		if err != nil {
+			return err
+		}
+
+		if webhook != "" {
+			cfg.notifiers = append(cfg.notifiers, &webhookNotifier{url: webhook})
+		}
+
+		command, err := cmd.Flags().GetString("exec")
+		if err != nil {
+			return err
+		}
+
+		if command != "" {
+			cfg.notifiers = append(cfg.notifiers, &execNotifier{command: command})
+		}
+
+		// Stop watching on SIGINT or SIGTERM.
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		return watchAction(ctx, os.Stdout, hostsFile, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	addScanFlags(watchCmd)
+	watchCmd.Flags().DurationP("interval", "i", 5*time.Minute, "time to wait between two scans")
+	watchCmd.Flags().IntP("count", "c", 0, "number of scans to run before exiting, 0 means forever")
+	watchCmd.Flags().String("log-file", "", "also append the changes to this file")
+	watchCmd.Flags().String("webhook", "", "also POST the changes as JSON to this URL")
+	watchCmd.Flags().String("exec", "", "also run this shell command with the changes as JSON on its stdin")
+}
+
+// watchConfig groups the settings of the watch command.
+type watchConfig struct {
+	scanConfig
+	interval time.Duration
+	// count is the number of scans to run, the watch goes on until ctx ends when it is 0.
+	count     int
+	notifiers []notifier
+}
+
+// watchAction scans the hosts on every interval and hands the changes between
+// two consecutive scans to the notifiers. The first scan is the baseline of the comparison.
+// The hosts file is loaded before every scan, so that the hosts added in the meantime are watched as well.
+// It returns when ctx ends or after cfg.count scans.
+func watchAction(ctx context.Context, out io.Writer, hostsFile string, cfg watchConfig) error {
+	if cfg.interval <= 0 {
+		return fmt.Errorf("invalid interval %s, it must be positive", cfg.interval)
+	}
+
+	var previous []scan.Results
+
+	for i := 0; cfg.count == 0 || i < cfg.count; i++ {
+		if i > 0 {
+			timer := time.NewTimer(cfg.interval)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+
+		hl := &scan.HostsList{}
+		if err := hl.Load(hostsFile); err != nil {
+			return err
+		}
+
+		results, err := scan.RunContext(ctx, hl.Select(cfg.groups, cfg.tags), cfg.ports, cfg.opts)
+		if err != nil {
+			// The watch got stopped during the scan. A partial scan would report
+			// the hosts it did not reach as disappeared, so it is dropped.
+			return nil
+		}
+
+		now := time.Now()
+
+		if previous == nil {
+			fmt.Fprintf(out, "%s: baseline scan of %d hosts done\n", now.Format(time.RFC3339), len(results))
+		} else if changes := scan.Diff(previous, results); len(changes) > 0 {
+			notifyAll(ctx, cfg.notifiers, now, changes)
+		}
+
+		previous = results
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// writerFunc runs a callback on every write, so that tests can act between two scans.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestWatchAction(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// Close the port once the baseline scan is reported, the next scan must see it closed.
+	var out bytes.Buffer
+	baseline := writerFunc(func(p []byte) (int, error) {
+		ln.Close()
+		return out.Write(p)
+	})
+
+	var changes bytes.Buffer
+	cfg := watchConfig{
+		scanConfig: scanConfig{ports: []int{port}},
+		interval:   10 * time.Millisecond,
+		count:      3,
+		notifiers:  []notifier{&writerNotifier{out: &changes}},
+	}
+
+	if err := watchAction(context.Background(), baseline, tf, cfg); err != nil {
+		t.Fatalf("expected no error, but got %q\n", err)
+	}
+
+	if !strings.Contains(out.String(), "baseline scan of 1 hosts done") {
+		t.Errorf("expected the baseline scan to be reported, got %q instead\n", out.String())
+	}
+
+	// The third scan finds the port closed again, which is not a change.
+	expectedChange := fmt.Sprintf("localhost: port %d/tcp closed (open -> closed)\n", port)
+	lines := strings.SplitAfter(strings.TrimSuffix(changes.String(), "\n"), "\n")
+
+	if len(lines) != 1 || !strings.HasSuffix(lines[0]+"\n", expectedChange) {
+		t.Errorf("expected a single change %q, got %q instead\n", expectedChange, changes.String())
+	}
+}
+
+func TestWatchActionCancelled(t *testing.T) {
+	tf, cleanup := setup(t, []string{"localhost"}, true)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := watchConfig{scanConfig: scanConfig{ports: []int{1}}, interval: time.Hour}
+	if err := watchAction(ctx, io.Discard, tf, cfg); err != nil {
+		t.Errorf("expected a stopped watch to return no error, but got %q\n", err)
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	changes := []scan.Change{{Kind: scan.HostAppeared, Host: "host1"}}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Webhook", func(t *testing.T) {
+		var event changeEvent
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("expected a POST request, got %s instead\n", r.Method)
+			}
+
+			if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+				t.Error(err)
+			}
+		}))
+		defer server.Close()
+
+		n := &webhookNotifier{url: server.URL}
+		if err := n.notify(context.Background(), at, changes); err != nil {
+			t.Fatalf("expected no error, but got %q\n", err)
+		}
+
+		if !event.Time.Equal(at) || len(event.Changes) != 1 || event.Changes[0].Host != "host1" {
+			t.Errorf("expected the changes to be posted, got %+v instead\n", event)
+		}
+	})
+
+	t.Run("WebhookError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		n := &webhookNotifier{url: server.URL}
+		if err := n.notify(context.Background(), at, changes); err == nil {
+			t.Errorf("expected an error for a failing webhook, got nil instead\n")
+		}
+	})
+
+	t.Run("Exec", func(t *testing.T) {
+		outFile := filepath.Join(t.TempDir(), "changes.json")
+
+		n := &execNotifier{command: "cat > " + outFile}
+		if err := n.notify(context.Background(), at, changes); err != nil {
+			t.Fatalf("expected no error, but got %q\n", err)
+		}
+
+		data, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `{"time":"2024-01-02T03:04:05Z","changes":[{"kind":"appeared","host":"host1"}]}`
+		if string(data) != expected {
+			t.Errorf("expected the command to get %s, got %s instead\n", expected, data)
+		}
+	})
+}
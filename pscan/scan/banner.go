@@ -0,0 +1,135 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxBannerLength caps the banner kept on the port state, the rest is only used for fingerprinting.
+const maxBannerLength = 256
+
+// clientFirstProbes holds the payloads of the services which wait for the client to speak first,
+// keyed by the service name of the port. Sending them right away saves waiting for a greeting
+// which is never going to come.
+var clientFirstProbes = map[string][]byte{
+	"http":          []byte("HEAD / HTTP/1.0\r\n\r\n"),
+	"http-alt":      []byte("HEAD / HTTP/1.0\r\n\r\n"),
+	"redis":         []byte("INFO server\r\n"),
+	"memcached":     []byte("version\r\n"),
+	"elasticsearch": []byte("GET / HTTP/1.0\r\n\r\n"),
+}
+
+// fallbackProbe is sent when a service stays silent after the connection.
+// HTTP is the most common protocol on non standard ports, so it is worth a try.
+var fallbackProbe = []byte("HEAD / HTTP/1.0\r\n\r\n")
+
+// fingerprint matches a banner to a service. The first submatch of the pattern, if any, is the version.
+type fingerprint struct {
+	service string
+	pattern *regexp.Regexp
+}
+
+// fingerprints are checked in order, the first match wins.
+var fingerprints = []fingerprint{
+	{"ssh", regexp.MustCompile(`^SSH-[\d.]+-(\S+)`)},
+	{"http", regexp.MustCompile(`(?is)^HTTP/\d(?:\.\d)? \d{3}.*?\r?\nServer: *([^\r\n]+)`)},
+	{"http", regexp.MustCompile(`^HTTP/\d(?:\.\d)? \d{3}`)},
+	{"redis", regexp.MustCompile(`redis_version:(\S+)`)},
+	{"redis", regexp.MustCompile(`^(?:\+PONG|-NOAUTH|-ERR)`)},
+	{"memcached", regexp.MustCompile(`^VERSION (\S+)`)},
+	{"smtp", regexp.MustCompile(`^220[ -]\S+ E?SMTP ?(\S*)`)},
+	{"ftp", regexp.MustCompile(`(?i)^220[ -]\(vsFTPd ([\d.]+)\)`)},
+	{"ftp", regexp.MustCompile(`(?i)^220[ -].*FTP`)},
+	{"pop3", regexp.MustCompile(`^\+OK ?(\S*)`)},
+	{"imap", regexp.MustCompile(`^\* OK.*?IMAP`)},
+	// MySQL sends a binary handshake: protocol version 10 followed by the null terminated server version.
+	{"mysql", regexp.MustCompile(`^.{4}\x0a([\w.\-]+)\x00`)},
+}
+
+// grabBanner reads what the service on an open connection says, probing it when it stays silent,
+// and records the banner and the guessed service on p.
+// Banner grabbing is best effort, a failure leaves the port state untouched apart from the service guess.
+func grabBanner(ctx context.Context, conn net.Conn, p *PortState, timeout time.Duration) {
+	// Unblock the reads below as soon as ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	var data []byte
+	if probe, ok := clientFirstProbes[ServiceName(p.Port)]; ok {
+		data = exchange(conn, probe, timeout)
+	} else {
+		data = exchange(conn, nil, timeout)
+
+		if len(data) == 0 && ctx.Err() == nil {
+			data = exchange(conn, fallbackProbe, timeout)
+		}
+	}
+
+	p.Banner = cleanBanner(data)
+	p.Service, p.Version = fingerprintBanner(data)
+
+	if p.Service == "" {
+		p.Service = ServiceName(p.Port)
+	}
+}
+
+// exchange sends the probe, if any, and returns the first answer read within the timeout.
+func exchange(conn net.Conn, probe []byte, timeout time.Duration) []byte {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil
+	}
+
+	if len(probe) > 0 {
+		if _, err := conn.Write(probe); err != nil {
+			return nil
+		}
+	}
+
+	buf := make([]byte, 4096)
+	n, _ := conn.Read(buf)
+
+	return buf[:n]
+}
+
+func fingerprintBanner(data []byte) (string, string) {
+	for _, fp := range fingerprints {
+		m := fp.pattern.FindSubmatch(data)
+		if m == nil {
+			continue
+		}
+
+		version := ""
+		if len(m) > 1 {
+			version = strings.TrimSpace(string(m[1]))
+		}
+
+		return fp.service, version
+	}
+
+	return "", ""
+}
+
+// cleanBanner keeps the first line of the banner and replaces the non printable characters,
+// so that it is safe to show on a terminal.
+func cleanBanner(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+
+	line = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == 0xfffd {
+			return '.'
+		}
+
+		return r
+	}, strings.TrimRight(line, "\r\n"))
+
+	if len(line) > maxBannerLength {
+		line = strings.ToValidUTF8(line[:maxBannerLength], "")
+	}
+
+	return line
+}
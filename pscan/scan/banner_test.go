@@ -0,0 +1,134 @@
+package scan_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// serve starts a local stand-in for a service and returns its port.
+// The handler gets every accepted connection, which is closed once the handler returns.
+func serve(t *testing.T, handler func(conn net.Conn)) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				handler(conn)
+			}()
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestRunBanner(t *testing.T) {
+	testCases := []struct {
+		name            string
+		handler         func(conn net.Conn)
+		expectedService string
+		expectedVersion string
+		expectedBanner  string
+	}{
+		{
+			name: "SSH",
+			handler: func(conn net.Conn) {
+				conn.Write([]byte("SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n"))
+			},
+			expectedService: "ssh",
+			expectedVersion: "OpenSSH_9.6p1",
+			expectedBanner:  "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13",
+		},
+		{
+			name: "SMTP",
+			handler: func(conn net.Conn) {
+				conn.Write([]byte("220 mail.example.com ESMTP Postfix (Ubuntu)\r\n"))
+			},
+			expectedService: "smtp",
+			expectedVersion: "Postfix",
+			expectedBanner:  "220 mail.example.com ESMTP Postfix (Ubuntu)",
+		},
+		{
+			// HTTP servers wait for a request, so the fallback probe has to be sent.
+			name: "HTTP",
+			handler: func(conn net.Conn) {
+				if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+					return
+				}
+
+				conn.Write([]byte("HTTP/1.0 200 OK\r\nServer: nginx/1.25.3\r\n\r\n"))
+			},
+			expectedService: "http",
+			expectedVersion: "nginx/1.25.3",
+			expectedBanner:  "HTTP/1.0 200 OK",
+		},
+		{
+			name: "Redis",
+			handler: func(conn net.Conn) {
+				if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+					return
+				}
+
+				conn.Write([]byte("$40\r\n# Server\r\nredis_version:7.2.4\r\n"))
+			},
+			expectedService: "redis",
+			expectedVersion: "7.2.4",
+			expectedBanner:  "$40",
+		},
+		{
+			// A service which neither greets nor answers is left with an empty banner.
+			name:            "Silent",
+			handler:         func(conn net.Conn) { bufio.NewReader(conn).ReadString(0) },
+			expectedService: "",
+			expectedVersion: "",
+			expectedBanner:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			port := serve(t, tc.handler)
+
+			hl := scan.HostsList{}
+			hl.Add("localhost")
+
+			res := scan.Run(&hl, []int{port}, scan.Options{Banner: true})
+
+			if len(res) != 1 || len(res[0].PortStates) != 1 {
+				t.Fatalf("expected 1 result with 1 port state, got %v instead\n", res)
+			}
+
+			ps := res[0].PortStates[0]
+			if ps.State != scan.StateOpen {
+				t.Fatalf("expected port to be open, got %s instead\n", ps.State)
+			}
+
+			if ps.Service != tc.expectedService {
+				t.Errorf("expected service %q, got %q instead\n", tc.expectedService, ps.Service)
+			}
+
+			if ps.Version != tc.expectedVersion {
+				t.Errorf("expected version %q, got %q instead\n", tc.expectedVersion, ps.Version)
+			}
+
+			if ps.Banner != tc.expectedBanner {
+				t.Errorf("expected banner %q, got %q instead\n", tc.expectedBanner, ps.Banner)
+			}
+		})
+	}
+}
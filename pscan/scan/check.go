@@ -0,0 +1,91 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+)
+
+// HostProblem is a problem CheckHosts found with an entry of the hosts list,
+// or with one of the hosts a pattern expands into.
+type HostProblem struct {
+	Host string
+	Err  error
+}
+
+// String converts the problem to a human readable line.
+func (p HostProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Host, p.Err)
+}
+
+// CheckHosts reports the problems of a hosts list, typically one loaded from a hand edited file.
+// The entries are checked first, in the order of the list:
+//   - entries which are not valid hosts, see NormalizeHost
+//   - entries which are not written in their canonical form
+//   - entries which are the same host as an earlier entry
+//   - entries with a port override which cannot be parsed
+//
+// Then the host names which cannot be resolved are reported, those the patterns expand into included.
+// The lookups run concurrently, up to opts.Workers at a time, and the returned error
+// is only set when ctx ends before they complete.
+func CheckHosts(ctx context.Context, hl *HostsList, opts Options) ([]HostProblem, error) {
+	problems := []HostProblem{}
+	entries := map[string]string{}
+	names := []string{}
+	seenNames := map[string]bool{}
+
+	for _, entry := range hl.Hosts {
+		canonical, err := NormalizeHost(entry)
+		if err != nil {
+			problems = append(problems, HostProblem{Host: entry, Err: err})
+			continue
+		}
+
+		if first, ok := entries[canonical]; ok {
+			problems = append(problems, HostProblem{Host: entry, Err: fmt.Errorf("%w:same host as %s", ErrDuplicateHost, first)})
+			continue
+		}
+
+		entries[canonical] = entry
+
+		if canonical != entry {
+			problems = append(problems, HostProblem{Host: entry, Err: fmt.Errorf("%w:write it as %s", ErrNotCanonical, canonical)})
+		}
+
+		// A broken port override is ignored by the scans, see HostsList.targets.
+		if err := hl.Info[entry].validate(); err != nil {
+			problems = append(problems, HostProblem{Host: entry, Err: err})
+		}
+
+		// A valid entry always expands.
+		hosts, _ := ExpandHost(canonical)
+		for _, h := range hosts {
+			if _, err := netip.ParseAddr(h); err == nil || seenNames[h] {
+				continue
+			}
+
+			seenNames[h] = true
+			names = append(names, h)
+		}
+	}
+
+	unresolved := make([]bool, len(names))
+
+	parallel(ctx, len(names), opts.workers(), func(i int) {
+		if _, err := opts.resolver().LookupHost(ctx, names[i]); err != nil && ctx.Err() == nil {
+			unresolved[i] = true
+		}
+	})
+
+	if ctx.Err() != nil {
+		return problems, ctx.Err()
+	}
+
+	for i, name := range names {
+		if unresolved[i] {
+			problems = append(problems, HostProblem{Host: name, Err: ErrUnresolved})
+		}
+	}
+
+	return problems, nil
+}
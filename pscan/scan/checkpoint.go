@@ -0,0 +1,102 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCheckpointInterval is how often the progress of a scan is saved when Options does not define it.
+const DefaultCheckpointInterval = 10 * time.Second
+
+// Checkpoint is the progress of a scan saved to a file, so that an interrupted scan can be resumed.
+type Checkpoint struct {
+	Time time.Time `json:"time"`
+	// Results hold the hosts and the ports scanned so far, like the results of an interrupted scan.
+	Results []Results `json:"results"`
+}
+
+// LoadCheckpoint reads a checkpoint saved by a scan, see Options.Checkpoint.
+func LoadCheckpoint(checkpointFile string) (*Checkpoint, error) {
+	data, err := os.ReadFile(checkpointFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("cannot read the checkpoint %s: %w", checkpointFile, err)
+	}
+
+	return cp, nil
+}
+
+// SaveCheckpoint writes a checkpoint to a file. The file is replaced atomically,
+// so that a scan killed while saving still leaves the previous checkpoint behind.
+func SaveCheckpoint(checkpointFile string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(checkpointFile, data, 0644)
+}
+
+func (o Options) checkpointInterval() time.Duration {
+	if o.CheckpointInterval <= 0 {
+		return DefaultCheckpointInterval
+	}
+
+	return o.CheckpointInterval
+}
+
+// startCheckpoints saves the snapshots of a scan to opts.Checkpoint every interval until stop is called.
+// A failed save keeps the previous checkpoint, only the final save of RunContext is reported.
+func startCheckpoints(opts Options, snapshot func() []Results) (stop func()) {
+	if opts.Checkpoint == "" {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(opts.checkpointInterval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				SaveCheckpoint(opts.Checkpoint, Checkpoint{Time: time.Now(), Results: snapshot()})
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// resumeKey identifies the results of a target across the runs of a scan.
+// The address is part of it, a host which resolves to another address is scanned again.
+type resumeKey struct {
+	host    string
+	address string
+}
+
+func resumeIndex(results []Results) map[resumeKey]Results {
+	index := map[resumeKey]Results{}
+	for _, r := range results {
+		index[resumeKey{r.Host, r.Address}] = r
+	}
+
+	return index
+}
@@ -0,0 +1,170 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// recordingProber reports every port as open and records the probed ports.
+type recordingProber struct {
+	mu     sync.Mutex
+	probed []int
+	// onProbe runs before the answer, it can interrupt the scan.
+	onProbe func(port int)
+}
+
+func (p *recordingProber) Probe(ctx context.Context, address string, port int, opts scan.Options) (scan.PortState, error) {
+	p.mu.Lock()
+	p.probed = append(p.probed, port)
+	p.mu.Unlock()
+
+	if p.onProbe != nil {
+		p.onProbe(port)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return scan.PortState{}, err
+	}
+
+	return scan.PortState{Port: port, Protocol: scan.ProtocolTCP, State: scan.StateOpen}, nil
+}
+
+func openPorts(r scan.Results) []int {
+	ports := []int{}
+	for _, ps := range r.PortStates {
+		if ps.State == scan.StateOpen {
+			ports = append(ports, ps.Port)
+		}
+	}
+
+	return ports
+}
+
+func TestRunResume(t *testing.T) {
+	resolver := fakeResolver{addrs: map[string][]string{
+		"host1": {"10.0.0.1"},
+		"host2": {"10.0.0.2"},
+	}}
+
+	hl := &scan.HostsList{}
+	for _, host := range []string{"host1", "host2"} {
+		if err := hl.Add(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resume := []scan.Results{
+		{Host: "host1", Address: "10.0.0.1", PortStates: []scan.PortState{
+			{Port: 22, Protocol: scan.ProtocolTCP, State: scan.StateClosed, Reason: "conn-refused"},
+		}},
+		// The host resolves to another address now, it is scanned again.
+		{Host: "host2", Address: "10.0.0.9", PortStates: []scan.PortState{
+			{Port: 22, Protocol: scan.ProtocolTCP, State: scan.StateClosed},
+		}},
+	}
+
+	prober := &recordingProber{}
+	opts := scan.Options{Resolver: resolver, Prober: prober, NoDiscovery: true, Workers: 1, Resume: resume}
+
+	res, err := scan.RunContext(context.Background(), hl, []int{22, 80}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	slices.Sort(prober.probed)
+
+	expectedProbed := []int{22, 80, 80}
+	if !reflect.DeepEqual(prober.probed, expectedProbed) {
+		t.Errorf("expected probed ports %v, got %v instead\n", expectedProbed, prober.probed)
+	}
+
+	if len(res) != 2 || len(res[0].PortStates) != 2 || len(res[1].PortStates) != 2 {
+		t.Fatalf("expected 2 results with 2 port states, got %v instead\n", res)
+	}
+
+	// The resumed state is merged in the order of the ports.
+	if res[0].PortStates[0] != resume[0].PortStates[0] {
+		t.Errorf("expected the resumed state %v, got %v instead\n", resume[0].PortStates[0], res[0].PortStates[0])
+	}
+
+	if ports := openPorts(res[1]); !reflect.DeepEqual(ports, []int{22, 80}) {
+		t.Errorf("expected open ports %v on host2, got %v instead\n", []int{22, 80}, ports)
+	}
+}
+
+func TestRunCheckpoint(t *testing.T) {
+	resolver := fakeResolver{addrs: map[string][]string{"host1": {"10.0.0.1"}}}
+
+	hl := &scan.HostsList{}
+	if err := hl.Add("host1"); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpointFile := filepath.Join(t.TempDir(), "scan.checkpoint")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	prober := &recordingProber{onProbe: func(port int) {
+		if port != 80 {
+			return
+		}
+
+		// Wait for a periodic checkpoint with port 22, then interrupt the scan.
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			cp, err := scan.LoadCheckpoint(checkpointFile)
+			if err == nil && len(cp.Results) == 1 && len(cp.Results[0].PortStates) == 1 {
+				break
+			}
+		}
+
+		cancel()
+	}}
+
+	opts := scan.Options{
+		Resolver:           resolver,
+		Prober:             prober,
+		NoDiscovery:        true,
+		Workers:            1,
+		Checkpoint:         checkpointFile,
+		CheckpointInterval: 10 * time.Millisecond,
+	}
+
+	_, err := scan.RunContext(ctx, hl, []int{22, 80, 443}, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected error %q, got %q instead\n", context.Canceled, err)
+	}
+
+	cp, err := scan.LoadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if len(cp.Results) != 1 || !reflect.DeepEqual(openPorts(cp.Results[0]), []int{22}) {
+		t.Fatalf("expected a checkpoint with port 22 of host1, got %v instead\n", cp.Results)
+	}
+
+	// Resuming from the checkpoint only scans the remaining ports.
+	prober = &recordingProber{}
+	opts = scan.Options{Resolver: resolver, Prober: prober, NoDiscovery: true, Workers: 1, Resume: cp.Results}
+
+	res, err := scan.RunContext(context.Background(), hl, []int{22, 80, 443}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if !reflect.DeepEqual(prober.probed, []int{80, 443}) {
+		t.Errorf("expected probed ports %v, got %v instead\n", []int{80, 443}, prober.probed)
+	}
+
+	if len(res) != 1 || !reflect.DeepEqual(openPorts(res[0]), []int{22, 80, 443}) {
+		t.Errorf("expected ports 22, 80 and 443 open on host1, got %v instead\n", res)
+	}
+}
@@ -0,0 +1,235 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ChangeKind is the kind of difference between two scans.
+type ChangeKind int
+
+const (
+	HostAppeared ChangeKind = iota
+	HostDisappeared
+	PortOpened
+	PortClosed
+)
+
+var changeKindNames = map[ChangeKind]string{
+	HostAppeared:    "appeared",
+	HostDisappeared: "disappeared",
+	PortOpened:      "opened",
+	PortClosed:      "closed",
+}
+
+// String converts the change kind to a human readable string.
+func (k ChangeKind) String() string {
+	return changeKindNames[k]
+}
+
+// MarshalText lets encoders write the change kind by its name.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText is the counterpart of MarshalText.
+func (k *ChangeKind) UnmarshalText(text []byte) error {
+	for kind, name := range changeKindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w:%q", ErrInvalidChange, text)
+}
+
+// Change is a single difference between two scans.
+// Port and Protocol are only set for the port changes.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Host string     `json:"host"`
+	// Address tells the results of a host apart when it was scanned on every one of its addresses.
+	Address  string `json:"address,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	// From and To are the states of the port in the old and the new scan.
+	From State `json:"-"`
+	To   State `json:"-"`
+}
+
+// MarshalJSON writes the port states only for the port changes,
+// a host change has no states and closed is the zero value of State.
+func (c Change) MarshalJSON() ([]byte, error) {
+	type change Change // Avoids calling MarshalJSON recursively.
+
+	v := struct {
+		change
+		From *State `json:"from,omitempty"`
+		To   *State `json:"to,omitempty"`
+	}{change: change(c)}
+
+	if c.Kind == PortOpened || c.Kind == PortClosed {
+		v.From, v.To = &c.From, &c.To
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON is the counterpart of MarshalJSON.
+func (c *Change) UnmarshalJSON(data []byte) error {
+	type change Change // Avoids calling UnmarshalJSON recursively.
+
+	v := struct {
+		*change
+		From *State `json:"from"`
+		To   *State `json:"to"`
+	}{change: (*change)(c)}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.From != nil {
+		c.From = *v.From
+	}
+
+	if v.To != nil {
+		c.To = *v.To
+	}
+
+	return nil
+}
+
+// String converts the change to a human readable line.
+func (c Change) String() string {
+	host := c.Host
+	if c.Address != "" {
+		host = fmt.Sprintf("%s (%s)", c.Host, c.Address)
+	}
+
+	switch c.Kind {
+	case HostAppeared, HostDisappeared:
+		return fmt.Sprintf("%s: host %s", host, c.Kind)
+	default:
+		return fmt.Sprintf("%s: port %d/%s %s (%s -> %s)", host, c.Port, c.Protocol, c.Kind, c.From, c.To)
+	}
+}
+
+// Diff compares two scans and returns the hosts which appeared or disappeared,
+// followed by the ports which opened or closed on the hosts found in both scans.
+// A host appears when it is found and not down in the new scan, but was missing, not found or down in the old one.
+// Ports are only compared when both scans checked them, a port added to the scan is not a change.
+func Diff(old, new []Results) []Change {
+	oldKeys, newKeys := resultKeys(old), resultKeys(new)
+
+	oldHosts := map[resultKey]Results{}
+	for i, r := range old {
+		oldHosts[oldKeys[i]] = r
+	}
+
+	newHosts := map[resultKey]Results{}
+	for i, r := range new {
+		newHosts[newKeys[i]] = r
+	}
+
+	changes := []Change{}
+
+	for i, r := range new {
+		if o, ok := oldHosts[newKeys[i]]; r.present() && (!ok || !o.present()) {
+			changes = append(changes, Change{Kind: HostAppeared, Host: r.Host, Address: newKeys[i].address})
+		}
+	}
+
+	for i, o := range old {
+		if r, ok := newHosts[oldKeys[i]]; o.present() && (!ok || !r.present()) {
+			changes = append(changes, Change{Kind: HostDisappeared, Host: o.Host, Address: oldKeys[i].address})
+		}
+	}
+
+	for i, r := range new {
+		o, ok := oldHosts[newKeys[i]]
+		if !ok || !r.present() || !o.present() {
+			continue
+		}
+
+		changes = append(changes, diffPorts(newKeys[i], o.PortStates, r.PortStates)...)
+	}
+
+	return changes
+}
+
+// resultKey identifies the results of a host across scans.
+// The address is only part of it when the host has several results in a scan, one per address,
+// so that a host which resolves to another address is still the same host.
+type resultKey struct {
+	host    string
+	address string
+}
+
+// resultKeys returns the key of every result of a scan.
+func resultKeys(results []Results) []resultKey {
+	count := map[string]int{}
+	for _, r := range results {
+		count[r.Host]++
+	}
+
+	keys := make([]resultKey, len(results))
+	for i, r := range results {
+		keys[i].host = r.Host
+
+		if count[r.Host] > 1 {
+			keys[i].address = r.Address
+		}
+	}
+
+	return keys
+}
+
+// present reports whether the host was found and did not look down, so that its ports were scanned.
+func (r Results) present() bool {
+	return !r.NotFound && r.Status != HostDown
+}
+
+func diffPorts(key resultKey, old, new []PortState) []Change {
+	type portKey struct {
+		port     int
+		protocol string
+	}
+
+	oldStates := map[portKey]State{}
+	for _, p := range old {
+		oldStates[portKey{p.Port, p.Protocol}] = p.State
+	}
+
+	changes := []Change{}
+
+	for _, p := range new {
+		from, ok := oldStates[portKey{p.Port, p.Protocol}]
+		if !ok {
+			continue
+		}
+
+		wasOpen, isOpen := from == StateOpen, p.State == StateOpen
+		if wasOpen == isOpen {
+			continue
+		}
+
+		kind := PortOpened
+		if wasOpen {
+			kind = PortClosed
+		}
+
+		changes = append(changes, Change{
+			Kind:     kind,
+			Host:     key.host,
+			Address:  key.address,
+			Port:     p.Port,
+			Protocol: p.Protocol,
+			From:     from,
+			To:       p.State,
+		})
+	}
+
+	return changes
+}
@@ -0,0 +1,135 @@
+package scan_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestDiff(t *testing.T) {
+	port := func(p int, s scan.State) scan.PortState {
+		return scan.PortState{Port: p, Protocol: scan.ProtocolTCP, State: s}
+	}
+
+	old := []scan.Results{
+		{Host: "stays", PortStates: []scan.PortState{
+			port(22, scan.StateOpen),
+			port(80, scan.StateClosed),
+			port(443, scan.StateOpen),
+			port(8080, scan.StateFiltered),
+		}},
+		{Host: "leaves", PortStates: []scan.PortState{port(22, scan.StateOpen)}},
+		{Host: "resolves", NotFound: true},
+		{Host: "goes-down", Status: scan.HostUp, PortStates: []scan.PortState{port(22, scan.StateOpen)}},
+	}
+
+	new := []scan.Results{
+		{Host: "stays", PortStates: []scan.PortState{
+			port(22, scan.StateOpen),
+			port(80, scan.StateOpen),
+			port(443, scan.StateFiltered),
+			port(8080, scan.StateClosed),
+			port(9090, scan.StateOpen),
+		}},
+		{Host: "resolves", PortStates: []scan.PortState{port(22, scan.StateOpen)}},
+		{Host: "joins"},
+		{Host: "goes-down", Status: scan.HostDown},
+	}
+
+	expected := []string{
+		"resolves: host appeared",
+		"joins: host appeared",
+		"leaves: host disappeared",
+		"goes-down: host disappeared",
+		"stays: port 80/tcp opened (closed -> open)",
+		"stays: port 443/tcp closed (open -> filtered)",
+	}
+
+	changes := scan.Diff(old, new)
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d instead: %v", len(expected), len(changes), changes)
+	}
+
+	for i, c := range changes {
+		if c.String() != expected[i] {
+			t.Errorf("expected change %q, got %q instead", expected[i], c.String())
+		}
+	}
+}
+
+func TestDiffAddresses(t *testing.T) {
+	result := func(address string, state scan.State) scan.Results {
+		return scan.Results{
+			Host:       "dual",
+			Address:    address,
+			PortStates: []scan.PortState{{Port: 22, Protocol: scan.ProtocolTCP, State: state}},
+		}
+	}
+
+	// A host scanned on every address is compared address by address.
+	old := []scan.Results{result("::1", scan.StateOpen), result("127.0.0.1", scan.StateClosed)}
+	new := []scan.Results{result("::1", scan.StateOpen), result("127.0.0.1", scan.StateOpen)}
+
+	changes := scan.Diff(old, new)
+
+	expected := "dual (127.0.0.1): port 22/tcp opened (closed -> open)"
+	if len(changes) != 1 || changes[0].String() != expected {
+		t.Errorf("expected change %q, got %v instead", expected, changes)
+	}
+
+	// A host scanned on a single address is the same host when it resolves to another one.
+	changes = scan.Diff(old[:1], []scan.Results{result("127.0.0.1", scan.StateOpen)})
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v instead", changes)
+	}
+}
+
+func TestChangeJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		change   scan.Change
+		expected string
+	}{
+		{
+			name:     "Host",
+			change:   scan.Change{Kind: scan.HostAppeared, Host: "host1"},
+			expected: `{"kind":"appeared","host":"host1"}`,
+		},
+		{
+			name: "Port",
+			change: scan.Change{
+				Kind:     scan.PortOpened,
+				Host:     "host1",
+				Port:     22,
+				Protocol: scan.ProtocolTCP,
+				From:     scan.StateClosed,
+				To:       scan.StateOpen,
+			},
+			expected: `{"kind":"opened","host":"host1","port":22,"protocol":"tcp","from":"closed","to":"open"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.change)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != tc.expected {
+				t.Errorf("expected JSON %s, got %s instead", tc.expected, data)
+			}
+
+			var decoded scan.Change
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("expected no error while decoding, got %q instead", err)
+			}
+
+			if decoded != tc.change {
+				t.Errorf("expected decoded change %+v, got %+v instead", tc.change, decoded)
+			}
+		})
+	}
+}
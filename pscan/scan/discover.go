@@ -0,0 +1,265 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// DefaultDiscoveryPorts are probed to tell whether a host is up when Options does not define them.
+// Any answer counts, a refused connection proves that the host is up just as well as an accepted one.
+var DefaultDiscoveryPorts = []int{80, 443, 22, 445, 3389}
+
+// HostStatus tells whether a host answered the discovery probes.
+type HostStatus int
+
+const (
+	// HostUnknown is used when the discovery is skipped or its probes fail locally.
+	HostUnknown HostStatus = iota
+	HostUp
+	HostDown
+)
+
+var hostStatusNames = map[HostStatus]string{
+	HostUnknown: "unknown",
+	HostUp:      "up",
+	HostDown:    "down",
+}
+
+// String converts the status to a human readable string.
+func (s HostStatus) String() string {
+	if name, ok := hostStatusNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("HostStatus(%d)", int(s))
+}
+
+// MarshalText lets encoders write the status as "up" or "down" instead of a number.
+func (s HostStatus) MarshalText() ([]byte, error) {
+	if _, ok := hostStatusNames[s]; !ok {
+		return nil, fmt.Errorf("%w:%d", ErrInvalidStatus, int(s))
+	}
+
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText is the counterpart of MarshalText, so that encoded results can be read back.
+func (s *HostStatus) UnmarshalText(text []byte) error {
+	for status, name := range hostStatusNames {
+		if name == string(text) {
+			*s = status
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w:%q", ErrInvalidStatus, text)
+}
+
+func (o Options) discoveryPorts() []int {
+	if len(o.DiscoveryPorts) == 0 {
+		return DefaultDiscoveryPorts
+	}
+
+	return o.DiscoveryPorts
+}
+
+// Discover checks which hosts of the list are up without scanning their ports.
+// The results keep the order of the hosts list, and like RunContext,
+// the results gathered so far are returned together with the context error when ctx ends early.
+func Discover(ctx context.Context, hl *HostsList, opts Options) ([]Results, error) {
+	targets := resolveTargets(ctx, hl.targets(nil), opts)
+	done := make([]bool, len(targets))
+	limiter := newRateLimiter(opts.Rate)
+
+	parallel(ctx, len(targets), opts.workers(), func(i int) {
+		t := &targets[i]
+
+		if !t.res.NotFound {
+			status, reason, err := discoverHost(ctx, t.res.Address, opts, limiter)
+			if err != nil {
+				return
+			}
+
+			t.res.Status, t.res.Reason = status, reason
+		}
+
+		done[i] = true
+	})
+
+	res := []Results{}
+	for i, t := range targets {
+		if done[i] {
+			res = append(res, t.res)
+		}
+	}
+
+	return res, ctx.Err()
+}
+
+// discoverHost tells whether a host is up by sending an ICMP echo request, when the privileges allow it,
+// together with TCP connect probes to the discovery ports. The first answer settles it,
+// the host is only down when none of the probes gets an answer.
+// The returned error is only set when ctx ends before the discovery completes.
+func discoverHost(ctx context.Context, addr string, opts Options, limiter *rateLimiter) (HostStatus, string, error) {
+	// Stop the remaining probes as soon as the host answers one of them.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type answer struct {
+		status HostStatus
+		reason string
+	}
+
+	ports := opts.discoveryPorts()
+	answers := make(chan answer, len(ports)+1)
+
+	go func() {
+		status, reason := ping(ctx, addr, opts.timeout())
+		answers <- answer{status, reason}
+	}()
+
+	for _, port := range ports {
+		go func(port int) {
+			if err := limiter.wait(ctx); err != nil {
+				answers <- answer{HostUnknown, ""}
+				return
+			}
+
+			status, reason := tcpPing(ctx, addr, port, opts)
+			answers <- answer{status, reason}
+		}(port)
+	}
+
+	result := answer{HostUnknown, ""}
+
+	for i := 0; i < len(ports)+1; i++ {
+		a := <-answers
+
+		switch {
+		case a.status == HostUp:
+			return a.status, a.reason, nil
+		case a.status == HostDown && result.status == HostUnknown:
+			result = a
+		}
+	}
+
+	// The probes of a cancelled discovery give up without an answer, which is not a down host.
+	if ctx.Err() != nil {
+		return HostUnknown, "", ctx.Err()
+	}
+
+	return result.status, result.reason, nil
+}
+
+// tcpPing probes a single TCP port of the address for discovery.
+func tcpPing(ctx context.Context, addr string, port int, opts Options) (HostStatus, string) {
+	p, err := scanTCPPort(ctx, addr, port, Options{Timeout: opts.Timeout, Dialer: opts.Dialer})
+	if err != nil {
+		return HostUnknown, ""
+	}
+
+	switch {
+	case p.State == StateOpen, p.State == StateClosed:
+		return HostUp, p.Reason
+	case p.State == StateFiltered && p.Reason != "admin-prohibited":
+		return HostDown, p.Reason
+	default:
+		// The probe failed before leaving this machine, it tells nothing about the host.
+		return HostUnknown, ""
+	}
+}
+
+// ICMP message types of the echo requests and replies.
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// ping sends an ICMP echo request to the address and waits for the reply.
+// Raw ICMP sockets need privileges, without them the status is unknown.
+func ping(ctx context.Context, addr string, timeout time.Duration) (HostStatus, string) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return HostUnknown, ""
+	}
+
+	network, request, reply := "ip4:icmp", byte(icmpv4EchoRequest), byte(icmpv4EchoReply)
+	if ip.To4() == nil {
+		network, request, reply = "ip6:ipv6-icmp", icmpv6EchoRequest, icmpv6EchoReply
+	}
+
+	conn, err := net.ListenPacket(network, "")
+	if err != nil {
+		return HostUnknown, ""
+	}
+
+	defer conn.Close()
+
+	// Unblock the read below as soon as ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	// Every raw socket receives all ICMP messages, the identifier tells our replies apart.
+	id := uint16(rand.Intn(1 << 16))
+
+	msg := []byte{request, 0, 0, 0, 0, 0, 0, 1, 'p', 's', 'c', 'a', 'n'}
+	binary.BigEndian.PutUint16(msg[4:], id)
+
+	// The kernel computes the checksum of ICMPv6 messages, but not of ICMPv4 ones.
+	if ip.To4() != nil {
+		binary.BigEndian.PutUint16(msg[2:], icmpChecksum(msg))
+	}
+
+	if _, err := conn.WriteTo(msg, &net.IPAddr{IP: ip}); err != nil {
+		return HostUnknown, ""
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 1500)
+
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return HostUnknown, ""
+			}
+
+			return HostDown, "no-response"
+		}
+
+		if n < 8 || buf[0] != reply || binary.BigEndian.Uint16(buf[4:]) != id {
+			continue
+		}
+
+		if addr, ok := from.(*net.IPAddr); ok && addr.IP.Equal(ip) {
+			return HostUp, "echo-reply"
+		}
+	}
+}
+
+// icmpChecksum computes the Internet checksum of an ICMP message, see RFC 1071.
+func icmpChecksum(msg []byte) uint16 {
+	var sum uint32
+
+	for i := 0; i+1 < len(msg); i += 2 {
+		sum += uint32(msg[i])<<8 | uint32(msg[i+1])
+	}
+
+	if len(msg)%2 == 1 {
+		sum += uint32(msg[len(msg)-1]) << 8
+	}
+
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+
+	return ^uint16(sum)
+}
@@ -0,0 +1,123 @@
+package scan_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestHostStatusText(t *testing.T) {
+	testCases := []struct {
+		status   scan.HostStatus
+		expected string
+	}{
+		{scan.HostUnknown, "unknown"},
+		{scan.HostUp, "up"},
+		{scan.HostDown, "down"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			text, err := tc.status.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(text) != tc.expected {
+				t.Errorf("expected %q, got %q instead\n", tc.expected, text)
+			}
+
+			var status scan.HostStatus
+			if err := status.UnmarshalText(text); err != nil {
+				t.Fatal(err)
+			}
+
+			if status != tc.status {
+				t.Errorf("expected %s, got %s instead\n", tc.status, status)
+			}
+		})
+	}
+
+	if _, err := scan.HostStatus(42).MarshalText(); err == nil {
+		t.Errorf("expected an error for an invalid status\n")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+	hl.Add("unknownhostoutthere")
+
+	// Nothing listens on a port closed right after it is opened, which answers the probe all the same.
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	res, err := scan.Discover(context.Background(), &hl, scan.Options{DiscoveryPorts: []int{port}})
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if len(res) != 2 {
+		t.Fatalf("expected 2 results, got %d instead\n", len(res))
+	}
+
+	if res[0].Host != "localhost" || res[0].Status != scan.HostUp {
+		t.Errorf("expected localhost to be up, got %+v instead\n", res[0])
+	}
+
+	if res[0].Reason == "" {
+		t.Errorf("expected a reason for localhost to be up\n")
+	}
+
+	if !res[1].NotFound || res[1].Status != scan.HostUnknown {
+		t.Errorf("expected unknown host not to be found, got %+v instead\n", res[1])
+	}
+}
+
+func TestRunDiscovery(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	testCases := []struct {
+		name     string
+		opts     scan.Options
+		expected scan.HostStatus
+	}{
+		{"Discovery", scan.Options{DiscoveryPorts: []int{port}}, scan.HostUp},
+		{"NoDiscovery", scan.Options{NoDiscovery: true}, scan.HostUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := scan.Run(&hl, []int{port}, tc.opts)
+
+			if len(res) != 1 || len(res[0].PortStates) != 1 {
+				t.Fatalf("expected 1 result with 1 port state, got %v instead\n", res)
+			}
+
+			if res[0].Status != tc.expected {
+				t.Errorf("expected status %s, got %s instead\n", tc.expected, res[0].Status)
+			}
+
+			if res[0].PortStates[0].State != scan.StateOpen {
+				t.Errorf("expected port %d to be open, got %s instead\n", port, res[0].PortStates[0].State)
+			}
+		})
+	}
+}
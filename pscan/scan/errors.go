@@ -0,0 +1,30 @@
+package scan
+
+import "errors"
+
+var (
+	ErrExists             = errors.New("host already in the list")
+	ErrNotExists          = errors.New("host not in the list")
+	ErrInvalidPort        = errors.New("invalid port")
+	ErrInvalidPortSpec    = errors.New("invalid port specification")
+	ErrUnknownService     = errors.New("unknown service")
+	ErrInvalidState       = errors.New("invalid port state")
+	ErrInvalidPattern     = errors.New("invalid host pattern")
+	ErrTooManyHosts       = errors.New("host pattern is too large")
+	ErrRecordNotFound     = errors.New("scan not in the history")
+	ErrInvalidChange      = errors.New("invalid change kind")
+	ErrInvalidStatus      = errors.New("invalid host status")
+	ErrInvalidAddressMode = errors.New("invalid address mode")
+	ErrUnknownMethod      = errors.New("unknown scan method")
+	ErrMethodExists       = errors.New("scan method already registered")
+	ErrInvalidFormat      = errors.New("invalid hosts file format")
+	ErrInvalidHost        = errors.New("invalid host")
+	ErrInvalidAddress     = errors.New("invalid IP address")
+	ErrInvalidHostName    = errors.New("invalid host name")
+	ErrNotCanonical       = errors.New("host not in canonical form")
+	ErrDuplicateHost      = errors.New("host listed more than once")
+	ErrUnresolved         = errors.New("host name cannot be resolved")
+	ErrInvalidPolicy      = errors.New("invalid policy")
+	ErrInvalidSeverity    = errors.New("invalid severity")
+	ErrLocked             = errors.New("file locked by another process")
+)
@@ -0,0 +1,253 @@
+package scan
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxExpansion is the upper limit of targets a single hosts list entry can expand into.
+// It keeps a mistyped prefix such as 10.0.0.0/8 from turning into millions of targets.
+const MaxExpansion = 1 << 16
+
+// lastOctetRange matches the short form of an IPv4 range, e.g. 10.0.0.1-50.
+var lastOctetRange = regexp.MustCompile(`^(\d{1,3}\.\d{1,3}\.\d{1,3}\.)(\d{1,3})-(\d{1,3})$`)
+
+// IsPattern reports whether a hosts list entry stands for more than one target.
+func IsPattern(entry string) bool {
+	if strings.ContainsAny(entry, "/[{") {
+		return true
+	}
+
+	first, _, ok := strings.Cut(entry, "-")
+	if !ok {
+		return false
+	}
+
+	// Host names can contain dashes as well, only an address on the left makes it a range.
+	_, err := netip.ParseAddr(first)
+	return err == nil
+}
+
+// ExpandHost expands a single hosts list entry into the concrete targets it stands for.
+// Besides plain host names and addresses, an entry can be:
+//   - a CIDR block, e.g. 192.168.1.0/24 or 2001:db8::/120
+//   - an address range, e.g. 10.0.0.1-50 or 10.0.0.1-10.0.1.10
+//   - a host name pattern with numeric ranges or alternatives, e.g. web[01-10].example.com or db-{a,b}
+//
+// The network and broadcast addresses of IPv4 blocks larger than /31 are not part of the targets.
+func ExpandHost(entry string) ([]string, error) {
+	if !IsPattern(entry) {
+		return []string{entry}, nil
+	}
+
+	switch {
+	case strings.Contains(entry, "/"):
+		return expandCIDR(entry)
+	case strings.ContainsAny(entry, "[{"):
+		return expandPattern(entry, "")
+	default:
+		return expandRange(entry)
+	}
+}
+
+// Targets expands the entries of the hosts list into the concrete targets to scan,
+// dropping the duplicates. An entry which cannot be expanded is kept as it is,
+// the scan then reports it as not found.
+func (hl *HostsList) Targets() []string {
+	targets := hl.targets(nil)
+
+	hosts := make([]string, 0, len(targets))
+	for _, t := range targets {
+		hosts = append(hosts, t.host)
+	}
+
+	return hosts
+}
+
+// target is a concrete host to scan together with its ports.
+type target struct {
+	host  string
+	ports []int
+}
+
+// targets expands the hosts list like Targets, pairing every target with the port override
+// of its entry, or with ports when the entry does not override them.
+func (hl *HostsList) targets(ports []int) []target {
+	targets := make([]target, 0, len(hl.Hosts))
+	seen := map[string]bool{}
+
+	for _, entry := range hl.Hosts {
+		hosts, err := ExpandHost(entry)
+		if err != nil {
+			hosts = []string{entry}
+		}
+
+		entryPorts := ports
+		if spec := hl.Info[entry].Ports; spec != "" {
+			// The override is validated when it is set, a broken one can only come from a hand edit.
+			if override, err := ParsePorts(spec); err == nil {
+				entryPorts = override
+			}
+		}
+
+		for _, h := range hosts {
+			if seen[h] {
+				continue
+			}
+
+			seen[h] = true
+			targets = append(targets, target{host: h, ports: entryPorts})
+		}
+	}
+
+	return targets
+}
+
+func expandCIDR(entry string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w:%s", ErrInvalidPattern, entry)
+	}
+
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("%w:%s expands to more than %d hosts", ErrTooManyHosts, entry, MaxExpansion)
+	}
+
+	first := prefix.Addr()
+	count := 1 << hostBits
+
+	// Skip the network and broadcast addresses, nothing to scan there.
+	if first.Is4() && hostBits > 1 {
+		first = first.Next()
+		count -= 2
+	}
+
+	hosts := make([]string, 0, count)
+	for a := first; len(hosts) < count; a = a.Next() {
+		hosts = append(hosts, a.String())
+	}
+
+	return hosts, nil
+}
+
+func expandRange(entry string) ([]string, error) {
+	from, to, _ := strings.Cut(entry, "-")
+
+	// Turn the short form 10.0.0.1-50 into 10.0.0.1-10.0.0.50.
+	if m := lastOctetRange.FindStringSubmatch(entry); m != nil {
+		to = m[1] + m[3]
+	}
+
+	first, err := netip.ParseAddr(from)
+	if err != nil {
+		return nil, fmt.Errorf("%w:%s", ErrInvalidPattern, entry)
+	}
+
+	last, err := netip.ParseAddr(to)
+	if err != nil || first.Is4() != last.Is4() || last.Less(first) {
+		return nil, fmt.Errorf("%w:%s", ErrInvalidPattern, entry)
+	}
+
+	hosts := []string{}
+	for a := first; ; a = a.Next() {
+		if len(hosts) == MaxExpansion {
+			return nil, fmt.Errorf("%w:%s expands to more than %d hosts", ErrTooManyHosts, entry, MaxExpansion)
+		}
+
+		hosts = append(hosts, a.String())
+
+		if a == last {
+			return hosts, nil
+		}
+	}
+}
+
+// expandPattern expands the first range or alternative group of the pattern and
+// recurses on the rest, prefixing the results with what is expanded so far.
+func expandPattern(pattern, prefix string) ([]string, error) {
+	start := strings.IndexAny(pattern, "[{")
+	if start == -1 {
+		if strings.ContainsAny(pattern, "]}") {
+			return nil, fmt.Errorf("%w:unbalanced brackets in %s", ErrInvalidPattern, prefix+pattern)
+		}
+
+		return []string{prefix + pattern}, nil
+	}
+
+	closing := "]"
+	if pattern[start] == '{' {
+		closing = "}"
+	}
+
+	end := strings.Index(pattern[start:], closing)
+	if end == -1 {
+		return nil, fmt.Errorf("%w:unbalanced brackets in %s", ErrInvalidPattern, prefix+pattern)
+	}
+
+	end += start
+	group := pattern[start+1 : end]
+
+	var parts []string
+	if closing == "}" {
+		parts = strings.Split(group, ",")
+	} else {
+		var err error
+		if parts, err = numericRange(group); err != nil {
+			return nil, fmt.Errorf("%w:%s", err, prefix+pattern)
+		}
+	}
+
+	hosts := []string{}
+	for _, part := range parts {
+		expanded, err := expandPattern(pattern[end+1:], prefix+pattern[:start]+part)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts = append(hosts, expanded...)
+		if len(hosts) > MaxExpansion {
+			return nil, fmt.Errorf("%w:%s expands to more than %d hosts", ErrTooManyHosts, prefix+pattern, MaxExpansion)
+		}
+	}
+
+	return hosts, nil
+}
+
+// numericRange expands a range such as 01-10, keeping the zero padding of the start.
+func numericRange(group string) ([]string, error) {
+	fromStr, toStr, ok := strings.Cut(group, "-")
+	if !ok {
+		return nil, fmt.Errorf("%w:[%s] is not a range", ErrInvalidPattern, group)
+	}
+
+	from, err := strconv.Atoi(fromStr)
+	if err != nil || from < 0 {
+		return nil, fmt.Errorf("%w:[%s] is not a numeric range", ErrInvalidPattern, group)
+	}
+
+	to, err := strconv.Atoi(toStr)
+	if err != nil || to < from {
+		return nil, fmt.Errorf("%w:[%s] is not a numeric range", ErrInvalidPattern, group)
+	}
+
+	if to-from >= MaxExpansion {
+		return nil, fmt.Errorf("%w:[%s] expands to more than %d hosts", ErrTooManyHosts, group, MaxExpansion)
+	}
+
+	format := "%d"
+	if len(fromStr) > 1 && fromStr[0] == '0' {
+		format = fmt.Sprintf("%%0%dd", len(fromStr))
+	}
+
+	parts := make([]string, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		parts = append(parts, fmt.Sprintf(format, i))
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,95 @@
+package scan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestExpandHost(t *testing.T) {
+	testCases := []struct {
+		name          string
+		entry         string
+		expectedHosts []string
+		expectedError error
+	}{
+		{"HostName", "my-host", []string{"my-host"}, nil},
+		{"Address", "10.0.0.1", []string{"10.0.0.1"}, nil},
+		{"CIDR", "192.168.1.0/30", []string{"192.168.1.1", "192.168.1.2"}, nil},
+		{"CIDRNotMasked", "192.168.1.5/30", []string{"192.168.1.5", "192.168.1.6"}, nil},
+		{"CIDRSingle", "192.168.1.5/32", []string{"192.168.1.5"}, nil},
+		{"CIDRPointToPoint", "192.168.1.4/31", []string{"192.168.1.4", "192.168.1.5"}, nil},
+		{"CIDRv6", "2001:db8::/126", []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}, nil},
+		{"ShortRange", "10.0.0.1-3", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, nil},
+		{"FullRange", "10.0.0.255-10.0.1.0", []string{"10.0.0.255", "10.0.1.0"}, nil},
+		{"RangeV6", "fe80::1-fe80::2", []string{"fe80::1", "fe80::2"}, nil},
+		{"NumericPattern", "web[08-10].lan", []string{"web08.lan", "web09.lan", "web10.lan"}, nil},
+		{"AlternativePattern", "db-{a,b}", []string{"db-a", "db-b"}, nil},
+		{"CombinedPattern", "{x,y}[1-2]", []string{"x1", "x2", "y1", "y2"}, nil},
+		{"InvalidCIDR", "10.0.0.0/33", nil, scan.ErrInvalidPattern},
+		{"HugeCIDR", "10.0.0.0/8", nil, scan.ErrTooManyHosts},
+		{"HugeCIDRv6", "2001:db8::/64", nil, scan.ErrTooManyHosts},
+		{"ReversedRange", "10.0.0.5-1", nil, scan.ErrInvalidPattern},
+		{"MixedRange", "10.0.0.1-fe80::1", nil, scan.ErrInvalidPattern},
+		{"UnbalancedPattern", "web[1-3.lan", nil, scan.ErrInvalidPattern},
+		{"LetterRange", "web[a-c]", nil, scan.ErrInvalidPattern},
+		{"HugePattern", "web[0-99999]", nil, scan.ErrTooManyHosts},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hosts, err := scan.ExpandHost(tc.entry)
+
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Errorf("expected error %q, got %q instead\n", tc.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %q instead\n", err)
+			}
+
+			if !slices.Equal(hosts, tc.expectedHosts) {
+				t.Errorf("expected hosts %v, got %v instead\n", tc.expectedHosts, hosts)
+			}
+		})
+	}
+}
+
+func TestExpandCIDRSize(t *testing.T) {
+	hosts, err := scan.ExpandHost("192.168.1.0/24")
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if len(hosts) != 254 {
+		t.Errorf("expected 254 hosts, got %d instead\n", len(hosts))
+	}
+
+	if hosts[0] != "192.168.1.1" || hosts[253] != "192.168.1.254" {
+		t.Errorf("expected hosts from 192.168.1.1 to 192.168.1.254, got %s to %s\n", hosts[0], hosts[253])
+	}
+}
+
+func TestTargets(t *testing.T) {
+	hl := scan.HostsList{}
+
+	for _, h := range []string{"10.0.0.1-2", "10.0.0.2", "host1"} {
+		if err := hl.Add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Entries which cannot be expanded, e.g. edited by hand, are scanned as they are.
+	hl.Hosts = append(hl.Hosts, "10.0.0.0/99")
+
+	expected := []string{"10.0.0.1", "10.0.0.2", "host1", "10.0.0.0/99"}
+	if targets := hl.Targets(); !slices.Equal(targets, expected) {
+		t.Errorf("expected targets %v, got %v instead\n", expected, targets)
+	}
+}
@@ -0,0 +1,113 @@
+package scan
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// nmapExportRun is the root element of the Nmap XML export, the hosts are marshalled like the results.
+type nmapExportRun struct {
+	XMLName xml.Name  `xml:"nmaprun"`
+	Scanner string    `xml:"scanner,attr"`
+	Hosts   []Results `xml:"host"`
+}
+
+// ExportHosts writes the hosts of the list to w in one of the import formats but ssh-known-hosts,
+// which needs the keys of the hosts.
+// The CSV export keeps the hosts as they are listed, with their groups, tags and port overrides,
+// while the other formats need concrete hosts, so the patterns are expanded.
+// The etc-hosts format needs the addresses of the hosts, those which cannot be resolved are skipped.
+func ExportHosts(ctx context.Context, w io.Writer, hl *HostsList, format string, opts Options) ([]SkippedEntry, error) {
+	switch format {
+	case FormatCSV:
+		return nil, exportCSV(w, hl)
+	case FormatNmapXML:
+		return nil, exportNmapXML(w, hl)
+	case FormatEtcHosts:
+		return exportEtcHosts(ctx, w, hl, opts)
+	case FormatKnownHosts:
+		return nil, fmt.Errorf("%w:%q cannot be exported, it needs the keys of the hosts", ErrInvalidFormat, format)
+	default:
+		return nil, fmt.Errorf("%w:%q, use one of %s, %s or %s", ErrInvalidFormat, format, FormatNmapXML, FormatEtcHosts, FormatCSV)
+	}
+}
+
+func exportCSV(w io.Writer, hl *HostsList) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"host", "groups", "tags", "ports"}); err != nil {
+		return err
+	}
+
+	for _, host := range hl.Hosts {
+		info := hl.Info[host]
+		record := []string{host, strings.Join(info.Groups, " "), strings.Join(info.Tags, " "), info.Ports}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportNmapXML(w io.Writer, hl *HostsList) error {
+	run := nmapExportRun{Scanner: "pscan"}
+
+	for _, host := range hl.Targets() {
+		r := Results{Host: host}
+		if net.ParseIP(host) != nil {
+			r.Address = host
+		}
+
+		run.Hosts = append(run.Hosts, r)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func exportEtcHosts(ctx context.Context, w io.Writer, hl *HostsList, opts Options) ([]SkippedEntry, error) {
+	skipped := []SkippedEntry{}
+
+	for _, host := range hl.Targets() {
+		if net.ParseIP(host) != nil {
+			skipped = append(skipped, SkippedEntry{Entry: host, Reason: "an address needs no entry"})
+			continue
+		}
+
+		addrs, err := opts.resolver().LookupHost(ctx, host)
+		if err != nil {
+			if ctx.Err() != nil {
+				return skipped, ctx.Err()
+			}
+
+			skipped = append(skipped, SkippedEntry{Entry: host, Reason: "cannot be resolved"})
+			continue
+		}
+
+		// An /etc/hosts entry has a single address, the address mode picks it like for the scans.
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", opts.AddressMode.pick(addrs)[0], host); err != nil {
+			return skipped, err
+		}
+	}
+
+	return skipped, nil
+}
@@ -0,0 +1,119 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Record is a single scan kept in the history store.
+type Record struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	// Partial is set when the scan got interrupted before it was complete.
+	Partial bool      `json:"partial,omitempty"`
+	Results []Results `json:"results"`
+}
+
+// OpenPorts counts the open ports across all hosts of the record.
+func (r Record) OpenPorts() int {
+	open := 0
+
+	for _, res := range r.Results {
+		for _, p := range res.PortStates {
+			if p.State == StateOpen {
+				open++
+			}
+		}
+	}
+
+	return open
+}
+
+// AppendHistory stores the results of a scan as a new record at the end of the history file,
+// which keeps one JSON record per line. The record gets the next free ID.
+func AppendHistory(historyFile string, rec Record) (Record, error) {
+	unlock, err := lockFile(historyFile + ".lock")
+	if err != nil {
+		return rec, err
+	}
+
+	defer unlock()
+
+	history, err := LoadHistory(historyFile)
+	if err != nil {
+		return rec, err
+	}
+
+	rec.ID = 1
+	if len(history) > 0 {
+		rec.ID = history[len(history)-1].ID + 1
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+
+	f, err := os.OpenFile(historyFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return rec, err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return rec, err
+	}
+
+	return rec, f.Close()
+}
+
+// LoadHistory reads all records of the history file, oldest first.
+// A missing history file is an empty history.
+func LoadHistory(historyFile string) ([]Record, error) {
+	f, err := os.Open(historyFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	defer f.Close()
+
+	history := []Record{}
+	scanner := bufio.NewScanner(f)
+	// Records of large scans easily exceed the default line limit of the scanner.
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("history file %s line %d: %w", historyFile, line, err)
+		}
+
+		history = append(history, rec)
+	}
+
+	return history, scanner.Err()
+}
+
+// FindRecord returns the record with the given ID from the history file.
+func FindRecord(historyFile string, id int) (Record, error) {
+	history, err := LoadHistory(historyFile)
+	if err != nil {
+		return Record{}, err
+	}
+
+	for _, rec := range history {
+		if rec.ID == id {
+			return rec, nil
+		}
+	}
+
+	return Record{}, fmt.Errorf("%w:%d", ErrRecordNotFound, id)
+}
@@ -0,0 +1,64 @@
+package scan_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestAppendLoadHistory(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "pScan.hosts.history")
+
+	// A missing history file is an empty history.
+	history, err := scan.LoadHistory(historyFile)
+	if err != nil {
+		t.Fatalf("expected no error but got %q instead", err)
+	}
+
+	if len(history) != 0 {
+		t.Fatalf("expected empty history, got %d records instead", len(history))
+	}
+
+	results := []scan.Results{
+		{
+			Host:       "host1",
+			PortStates: []scan.PortState{{Port: 22, Protocol: scan.ProtocolTCP, State: scan.StateOpen}},
+		},
+	}
+
+	for i := 1; i <= 3; i++ {
+		rec, err := scan.AppendHistory(historyFile, scan.Record{Time: time.Now(), Results: results})
+		if err != nil {
+			t.Fatalf("expected no error but got %q instead", err)
+		}
+
+		if rec.ID != i {
+			t.Errorf("expected record ID %d, got %d instead", i, rec.ID)
+		}
+	}
+
+	history, err = scan.LoadHistory(historyFile)
+	if err != nil {
+		t.Fatalf("expected no error but got %q instead", err)
+	}
+
+	if len(history) != 3 {
+		t.Fatalf("expected 3 records, got %d instead", len(history))
+	}
+
+	rec, err := scan.FindRecord(historyFile, 2)
+	if err != nil {
+		t.Fatalf("expected no error but got %q instead", err)
+	}
+
+	if rec.ID != 2 || rec.OpenPorts() != 1 || rec.Results[0].PortStates[0].State != scan.StateOpen {
+		t.Errorf("expected record 2 with 1 open port, got %+v instead", rec)
+	}
+
+	if _, err := scan.FindRecord(historyFile, 4); !errors.Is(err, scan.ErrRecordNotFound) {
+		t.Errorf("expected error %q, got %q instead", scan.ErrRecordNotFound, err)
+	}
+}
@@ -0,0 +1,131 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// structuredHosts is the structured format of the hosts file, e.g. in YAML:
+//
+//	hosts:
+//	  - name: db1.example.com
+//	    groups: [prod]
+//	    tags: [db]
+//	    ports: 22,5432
+//	  - name: 10.0.0.0/24
+type structuredHosts struct {
+	Hosts []hostEntry `yaml:"hosts" json:"hosts"`
+}
+
+type hostEntry struct {
+	Name     string `yaml:"name" json:"name"`
+	HostInfo `yaml:",inline"`
+}
+
+// parseStructured decodes data when it is a hosts file in the structured format,
+// that is a JSON object or a YAML mapping with the hosts key. It returns nil for the line format.
+// Deciding by the first line is not enough, a plain hosts file can start with a brace pattern.
+func parseStructured(data []byte) (*structuredHosts, error) {
+	var doc yaml.Node
+
+	// YAML is a superset of JSON, one decoder is enough for both.
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// Only a broken structured file starts like one, host names and patterns never do.
+		if first := firstLine(data); strings.HasPrefix(first, "hosts:") || strings.HasPrefix(first, `{"`) {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	mapping := doc.Content[0]
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != "hosts" {
+			continue
+		}
+
+		var f structuredHosts
+		if err := mapping.Decode(&f); err != nil {
+			return nil, err
+		}
+
+		return &f, nil
+	}
+
+	// The YAML decoder reads a line starting with a brace pattern, e.g. {web,db}.example.com,
+	// as a flow mapping, while it is no JSON object.
+	if mapping.Style&yaml.FlowStyle != 0 && !json.Valid(data) {
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("%w: the hosts key is missing", ErrInvalidFormat)
+}
+
+// firstLine returns the first line of data which is neither blank nor a comment.
+func firstLine(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+
+	return ""
+}
+
+// decode reads the entries as they are written, like the line format does.
+// Invalid and duplicate entries are left to Add and CheckHosts, so that a single one
+// does not make the whole list unusable.
+func (hl *HostsList) decode(f *structuredHosts) {
+	for _, e := range f.Hosts {
+		hl.Hosts = append(hl.Hosts, e.Name)
+
+		if e.HostInfo.IsZero() {
+			continue
+		}
+
+		if hl.Info == nil {
+			hl.Info = map[string]HostInfo{}
+		}
+
+		hl.Info[e.Name] = e.HostInfo
+	}
+}
+
+func (hl *HostsList) encode(hostsFile string) ([]byte, error) {
+	f := structuredHosts{Hosts: make([]hostEntry, 0, len(hl.Hosts))}
+
+	for _, host := range hl.Hosts {
+		f.Hosts = append(f.Hosts, hostEntry{Name: host, HostInfo: hl.Info[host]})
+	}
+
+	if strings.EqualFold(filepath.Ext(hostsFile), ".json") {
+		data, err := json.MarshalIndent(f, "", "  ")
+		return append(data, '\n'), err
+	}
+
+	return yaml.Marshal(f)
+}
+
+func (hl *HostsList) hasInfo() bool {
+	for _, info := range hl.Info {
+		if !info.IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
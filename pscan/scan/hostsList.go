@@ -4,20 +4,47 @@ package scan
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"sort"
-)
-
-var (
-	ErrExists    = errors.New("host already in the list")
-	ErrNotExists = errors.New("host not in the list")
+	"strings"
 )
 
 // HostList represents a list of hosts to run port scan
 type HostsList struct {
 	Hosts []string
+	// Info holds the optional groups, tags and port overrides of the hosts, keyed by host.
+	Info map[string]HostInfo
+
+	// structured is set when the list is loaded from a structured hosts file,
+	// so that saving it does not fall back to the plain text format.
+	structured bool
+}
+
+// HostInfo is the optional metadata of a host in the list.
+type HostInfo struct {
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+	Tags   []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Ports overrides the ports to scan on the host, it uses the syntax of ParsePorts.
+	Ports string `yaml:"ports,omitempty" json:"ports,omitempty"`
+}
+
+// IsZero reports whether the info carries no metadata.
+func (i HostInfo) IsZero() bool {
+	return len(i.Groups) == 0 && len(i.Tags) == 0 && i.Ports == ""
+}
+
+func (i HostInfo) validate() error {
+	if i.Ports == "" {
+		return nil
+	}
+
+	_, err := ParsePorts(i.Ports)
+	return err
 }
 
 func (hl *HostsList) search(host string) (bool, int) {
@@ -31,7 +58,16 @@ func (hl *HostsList) search(host string) (bool, int) {
 	return false, -1
 }
 
+// Add adds a host to the list. Besides host names and addresses, the host can be
+// a pattern such as a CIDR block or an address range, see ExpandHost for the details.
+// The host is validated and added in its canonical form, see NormalizeHost.
+// Patterns are stored as they are and only expanded when the hosts are scanned.
 func (hl *HostsList) Add(host string) error {
+	host, err := NormalizeHost(host)
+	if err != nil {
+		return err
+	}
+
 	if found, _ := hl.search(host); found {
 		return fmt.Errorf("%w:%s", ErrExists, host)
 	}
@@ -40,16 +76,98 @@ func (hl *HostsList) Add(host string) error {
 	return nil
 }
 
+// find searches a host as it is written, then in its canonical form,
+// so that hosts are found by any of their spellings while the invalid entries
+// of hand edited files can still be removed.
+func (hl *HostsList) find(host string) (bool, int) {
+	if found, i := hl.search(host); found {
+		return found, i
+	}
+
+	if canonical, err := NormalizeHost(host); err == nil {
+		return hl.search(canonical)
+	}
+
+	return false, -1
+}
+
 func (hl *HostsList) Remove(host string) error {
-	found, i := hl.search(host)
+	found, i := hl.find(host)
 	if !found {
 		return fmt.Errorf("%w:%s", ErrNotExists, host)
 	}
 
+	delete(hl.Info, hl.Hosts[i])
 	hl.Hosts = append(hl.Hosts[:i], hl.Hosts[i+1:]...)
 	return nil
 }
 
+// SetInfo sets the groups, tags and port overrides of a host in the list.
+func (hl *HostsList) SetInfo(host string, info HostInfo) error {
+	found, i := hl.find(host)
+	if !found {
+		return fmt.Errorf("%w:%s", ErrNotExists, host)
+	}
+
+	host = hl.Hosts[i]
+
+	if err := info.validate(); err != nil {
+		return fmt.Errorf("host %s: %w", host, err)
+	}
+
+	if info.IsZero() {
+		delete(hl.Info, host)
+		return nil
+	}
+
+	if hl.Info == nil {
+		hl.Info = map[string]HostInfo{}
+	}
+
+	hl.Info[host] = info
+	return nil
+}
+
+// Select returns a new list with the hosts which belong to any of the groups
+// and carry any of the tags. An empty groups or tags filter matches every host.
+func (hl *HostsList) Select(groups, tags []string) *HostsList {
+	selected := &HostsList{}
+
+	for _, host := range hl.Hosts {
+		info := hl.Info[host]
+
+		if len(groups) > 0 && !containsAny(info.Groups, groups) {
+			continue
+		}
+
+		if len(tags) > 0 && !containsAny(info.Tags, tags) {
+			continue
+		}
+
+		selected.Hosts = append(selected.Hosts, host)
+
+		if !info.IsZero() {
+			if selected.Info == nil {
+				selected.Info = map[string]HostInfo{}
+			}
+
+			selected.Info[host] = info
+		}
+	}
+
+	return selected
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, w := range wanted {
+		if slices.Contains(values, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (hl *HostsList) Load(hostsFile string) error {
 	f, err := os.Open(hostsFile)
 	if err != nil {
@@ -62,21 +180,58 @@ func (hl *HostsList) Load(hostsFile string) error {
 
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	// Structured hosts files carry groups and tags, the rest is the legacy one host per line format.
+	structured, err := parseStructured(data)
+	if err != nil {
+		return fmt.Errorf("cannot parse hosts file %s: %w", hostsFile, err)
+	}
+
+	if structured != nil {
+		hl.structured = true
+		hl.decode(structured)
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 
+	// Blank lines and comments make a hand written list easier to read, they are no hosts.
 	for scanner.Scan() {
-		hl.Hosts = append(hl.Hosts, scanner.Text())
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hl.Hosts = append(hl.Hosts, line)
 	}
 
-	return nil
+	return scanner.Err()
 }
 
+// Save writes the list to hostsFile. Lists with groups, tags or port overrides are saved
+// in the structured format, JSON if the file name ends with .json and YAML otherwise.
+// The rest are saved in the legacy one host per line format.
+// The file is replaced atomically, use UpdateHostsFile to also guard against concurrent updates.
 func (hl *HostsList) Save(hostsFile string) error {
+	if hl.structured || hl.hasInfo() {
+		data, err := hl.encode(hostsFile)
+		if err != nil {
+			return err
+		}
+
+		return writeFileAtomic(hostsFile, data, 0644)
+	}
+
 	output := ""
 
 	for _, host := range hl.Hosts {
 		output += fmt.Sprintln(host)
 	}
 
-	return os.WriteFile(hostsFile, []byte(output), 0644)
+	return writeFileAtomic(hostsFile, []byte(output), 0644)
 }
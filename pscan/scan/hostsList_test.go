@@ -3,6 +3,10 @@ package scan_test
 import (
 	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/acikgozb/cli-playground/pscan/scan"
@@ -17,6 +21,8 @@ func TestAdd(t *testing.T) {
 	}{
 		{"AddNew", "host2", 2, nil},
 		{"AddExisting", "host1", 1, scan.ErrExists},
+		{"AddCIDR", "10.0.0.0/24", 2, nil},
+		{"AddInvalidPattern", "10.0.0.0/40", 1, scan.ErrInvalidPattern},
 	}
 
 	for _, tc := range testCases {
@@ -168,3 +174,204 @@ func TestLoadNotExist(t *testing.T) {
 		t.Errorf("expected load to not return an error if file does not exist, but got %q", err)
 	}
 }
+
+func TestLoadComments(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "pScan.hosts")
+
+	data := "# databases\ndb1\n  db2  \n\n# web servers\nweb1\n"
+	if err := os.WriteFile(hostsFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		t.Fatalf("expected no error but got %q instead\n", err)
+	}
+
+	expected := []string{"db1", "db2", "web1"}
+	if !reflect.DeepEqual(hl.Hosts, expected) {
+		t.Errorf("expected hosts %q, got %q instead\n", expected, hl.Hosts)
+	}
+}
+
+func TestLoadBracePattern(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "pScan.hosts")
+
+	// A plain hosts file starting with a brace pattern is not a JSON object.
+	for _, host := range []string{"{web,db}.example.com", "other.example.com"} {
+		err := scan.UpdateHostsFile(hostsFile, func(hl *scan.HostsList) error {
+			return hl.Add(host)
+		})
+		if err != nil {
+			t.Fatalf("expected no error adding %s but got %q instead\n", host, err)
+		}
+	}
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		t.Fatalf("expected no error but got %q instead\n", err)
+	}
+
+	expected := []string{"{web,db}.example.com", "other.example.com"}
+	if !reflect.DeepEqual(hl.Hosts, expected) {
+		t.Errorf("expected hosts %q, got %q instead\n", expected, hl.Hosts)
+	}
+
+	data, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "{web,db}.example.com\nother.example.com\n" {
+		t.Errorf("expected the file to stay in the line format, got %q instead\n", data)
+	}
+}
+
+func TestLoadStructuredWithoutHosts(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "pScan.hosts")
+
+	if err := os.WriteFile(hostsFile, []byte("{\"host\": [\"db1\"]}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); !errors.Is(err, scan.ErrInvalidFormat) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrInvalidFormat, err)
+	}
+}
+
+func TestSaveLoadStructured(t *testing.T) {
+	testCases := []struct {
+		name           string
+		fileName       string
+		expectedPrefix string
+	}{
+		{"YAML", "pScan.hosts", "hosts:"},
+		{"JSON", "pScan.json", "{"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			hl1 := &scan.HostsList{}
+			hl2 := &scan.HostsList{}
+
+			for _, h := range []string{"db1", "web1"} {
+				if err := hl1.Add(h); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			info := scan.HostInfo{Groups: []string{"prod"}, Tags: []string{"db"}, Ports: "22,5432"}
+			if err := hl1.SetInfo("db1", info); err != nil {
+				t.Fatal(err)
+			}
+
+			hostsFile := filepath.Join(t.TempDir(), tc.fileName)
+
+			// When
+			if err := hl1.Save(hostsFile); err != nil {
+				t.Fatalf("expected no error while saving hl1 but got %q instead", err)
+			}
+
+			if err := hl2.Load(hostsFile); err != nil {
+				t.Fatalf("expected no error while loading hl2 but got %q instead", err)
+			}
+
+			// Then
+			data, err := os.ReadFile(hostsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(string(data), tc.expectedPrefix) {
+				t.Errorf("expected the file to start with %q, got %q instead", tc.expectedPrefix, data)
+			}
+
+			if !slices.Equal(hl1.Hosts, hl2.Hosts) {
+				t.Errorf("expected hosts %v, got %v instead", hl1.Hosts, hl2.Hosts)
+			}
+
+			if !reflect.DeepEqual(hl2.Info["db1"], info) {
+				t.Errorf("expected info %+v, got %+v instead", info, hl2.Info["db1"])
+			}
+
+			if !hl2.Info["web1"].IsZero() {
+				t.Errorf("expected web1 to have no info, got %+v instead", hl2.Info["web1"])
+			}
+
+			// Saving a structured list without any info left must keep the structured format.
+			if err := hl2.SetInfo("db1", scan.HostInfo{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := hl2.Save(hostsFile); err != nil {
+				t.Fatal(err)
+			}
+
+			if data, _ := os.ReadFile(hostsFile); !strings.HasPrefix(string(data), tc.expectedPrefix) {
+				t.Errorf("expected the file to stay structured, got %q instead", data)
+			}
+		})
+	}
+}
+
+func TestSetInfoInvalid(t *testing.T) {
+	hl := &scan.HostsList{}
+	if err := hl.Add("host1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hl.SetInfo("host2", scan.HostInfo{Tags: []string{"db"}}); !errors.Is(err, scan.ErrNotExists) {
+		t.Errorf("expected error %q, got %q instead", scan.ErrNotExists, err)
+	}
+
+	if err := hl.SetInfo("host1", scan.HostInfo{Ports: "0"}); !errors.Is(err, scan.ErrInvalidPort) {
+		t.Errorf("expected error %q, got %q instead", scan.ErrInvalidPort, err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	hl := &scan.HostsList{}
+
+	hosts := map[string]scan.HostInfo{
+		"db1":   {Groups: []string{"prod"}, Tags: []string{"db"}},
+		"db2":   {Groups: []string{"staging"}, Tags: []string{"db"}},
+		"web1":  {Groups: []string{"prod"}, Tags: []string{"web"}},
+		"other": {},
+	}
+
+	for h, info := range hosts {
+		if err := hl.Add(h); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := hl.SetInfo(h, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		groups        []string
+		tags          []string
+		expectedHosts []string
+	}{
+		{"NoFilter", nil, nil, []string{"db1", "db2", "other", "web1"}},
+		{"Group", []string{"prod"}, nil, []string{"db1", "web1"}},
+		{"Groups", []string{"prod", "staging"}, nil, []string{"db1", "db2", "web1"}},
+		{"Tag", nil, []string{"db"}, []string{"db1", "db2"}},
+		{"GroupAndTag", []string{"prod"}, []string{"db"}, []string{"db1"}},
+		{"NoMatch", []string{"dev"}, nil, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected := hl.Select(tc.groups, tc.tags)
+
+			if !slices.Equal(selected.Hosts, tc.expectedHosts) {
+				t.Errorf("expected hosts %v, got %v instead", tc.expectedHosts, selected.Hosts)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// UpdateHostsFile runs a read-modify-write transaction on hostsFile.
+// It takes an exclusive lock on the file, loads the hosts list, passes it to fn and
+// saves it back if fn succeeds. Concurrent updates of the same file are serialized,
+// so no update gets lost, and the file is replaced atomically, so readers never see
+// a partially written file.
+func UpdateHostsFile(hostsFile string, fn func(hl *HostsList) error) error {
+	unlock, err := lockFile(hostsFile + ".lock")
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	hl := &HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		return err
+	}
+
+	if err := fn(hl); err != nil {
+		return err
+	}
+
+	return hl.Save(hostsFile)
+}
+
+// writeFileAtomic writes data to a temporary file next to name and renames it over name,
+// so that name either has its old content or the new one, never a mix of both.
+// An existing file keeps its permissions, perm only applies to a new one.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	// The temp file is renamed on success, removing it afterwards is a no-op then.
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+
+	// Make sure the data is on disk before the rename makes it visible.
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(temp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), name)
+}
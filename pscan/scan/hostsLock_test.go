@@ -0,0 +1,124 @@
+package scan_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestUpdateHostsFileConcurrent(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "pScan.hosts")
+
+	// Every update adds its own host, a lost update would mean a missing host.
+	updates := 20
+	wg := sync.WaitGroup{}
+	errCh := make(chan error, updates)
+
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			errCh <- scan.UpdateHostsFile(hostsFile, func(hl *scan.HostsList) error {
+				return hl.Add(fmt.Sprintf("host%02d", i))
+			})
+		}(i)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		if err != nil {
+			t.Fatalf("expected no error but got %q instead", err)
+		}
+	}
+
+	hl := &scan.HostsList{}
+	if err := hl.Load(hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hl.Hosts) != updates {
+		t.Errorf("expected %d hosts, got %d instead: %v", updates, len(hl.Hosts), hl.Hosts)
+	}
+
+	// The lock file only exists while an update runs.
+	if _, err := os.Stat(hostsFile + ".lock"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no lock file left, got %v instead", err)
+	}
+}
+
+func TestUpdateHostsFileError(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "pScan.hosts")
+
+	hl := &scan.HostsList{}
+	if err := hl.Add("host1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hl.Save(hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failing transaction must leave the file untouched.
+	err := scan.UpdateHostsFile(hostsFile, func(hl *scan.HostsList) error {
+		if err := hl.Add("host2"); err != nil {
+			return err
+		}
+
+		return hl.Add("host1")
+	})
+	if !errors.Is(err, scan.ErrExists) {
+		t.Fatalf("expected error %q, got %q instead", scan.ErrExists, err)
+	}
+
+	data, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "host1\n" {
+		t.Errorf("expected the file to keep its content, got %q instead", data)
+	}
+
+	// No temporary files should be left behind.
+	entries, err := os.ReadDir(filepath.Dir(hostsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		if e.Name() != "pScan.hosts" && e.Name() != "pScan.hosts.lock" {
+			t.Errorf("expected no leftover files, found %q", e.Name())
+		}
+	}
+}
+
+func TestUpdateHostsFileKeepsMode(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "pScan.hosts")
+
+	if err := os.WriteFile(hostsFile, []byte("host1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scan.UpdateHostsFile(hostsFile, func(hl *scan.HostsList) error {
+		return hl.Add("host2")
+	}); err != nil {
+		t.Fatalf("expected no error but got %q instead", err)
+	}
+
+	info, err := os.Stat(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode %v, got %v instead", os.FileMode(0600), info.Mode().Perm())
+	}
+}
@@ -0,0 +1,143 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRedirects is the upper limit of redirects followed by the HTTP probe, like the one of net/http.
+const maxRedirects = 10
+
+// maxTitleBody is how much of a page is read to find its title.
+const maxTitleBody = 64 << 10
+
+var titleTag = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// HTTPInfo describes the answer of the web server on a port to a request of its root page.
+type HTTPInfo struct {
+	// URL is the first requested URL, its scheme tells whether the port speaks HTTPS.
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	// Server is the Server header of the response, which often names the software and its version.
+	Server string `json:"server,omitempty"`
+	Title  string `json:"title,omitempty"`
+	// Redirects are the locations the server redirected to, in order.
+	// Redirects to other hosts are recorded but not followed, StatusCode is then the one of the redirect.
+	Redirects []string `json:"redirects,omitempty"`
+	// ResponseTime is the time it took to get the final response, redirects included.
+	ResponseTime time.Duration `json:"responseTime"`
+}
+
+// StatusText returns the status code followed by its name, e.g. "200 OK".
+func (i HTTPInfo) StatusText() string {
+	return strings.TrimSpace(fmt.Sprintf("%d %s", i.StatusCode, http.StatusText(i.StatusCode)))
+}
+
+// probeHTTP requests the root page of an open TCP port, over HTTPS first and plain HTTP
+// when the port does not speak TLS. It returns nil when the port does not speak HTTP.
+// The requests name the host, so that virtual hosts answer like they would to a browser,
+// but they go to the scanned address. Certificates are not verified, see inspectTLS for that.
+func probeHTTP(ctx context.Context, host, address string, port int, opts Options) *HTTPInfo {
+	for _, scheme := range []string{"https", "http"} {
+		if info, err := fetchHTTP(ctx, scheme, host, address, port, opts); err == nil {
+			return info
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func fetchHTTP(ctx context.Context, scheme, host, address string, port int, opts Options) (*HTTPInfo, error) {
+	// Name the host in the URL, unless the scan only knows its address.
+	name := host
+	if net.ParseIP(host) != nil {
+		name = address
+	}
+
+	target := net.JoinHostPort(name, strconv.Itoa(port))
+	info := &HTTPInfo{URL: fmt.Sprintf("%s://%s/", scheme, target)}
+
+	serverName := name
+	if net.ParseIP(name) != nil {
+		serverName = ""
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// Pin the scanned host to the scanned address, the redirects within it included.
+			if addr == target {
+				addr = net.JoinHostPort(address, strconv.Itoa(port))
+			}
+
+			return dial(ctx, opts, network, addr)
+		},
+		TLSClientConfig:   &tls.Config{ServerName: serverName, InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   opts.timeout(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			info.Redirects = append(info.Redirects, req.URL.String())
+
+			// Stay on the scanned host, a scan should not wander off to other ones.
+			if req.URL.Hostname() != name || len(via) >= maxRedirects {
+				return http.ErrUseLastResponse
+			}
+
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, info.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	info.ResponseTime = time.Since(start)
+	info.StatusCode = resp.StatusCode
+	info.Server = resp.Header.Get("Server")
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTitleBody))
+	if err != nil {
+		// The status and the headers are worth keeping without the title.
+		return info, nil
+	}
+
+	info.Title = pageTitle(body)
+	return info, nil
+}
+
+// pageTitle returns the title of an HTML page on a single line, or an empty string without one.
+func pageTitle(body []byte) string {
+	m := titleTag.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+}
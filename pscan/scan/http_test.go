@@ -0,0 +1,137 @@
+package scan_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestRunHTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test-server/1.0")
+		fmt.Fprint(w, "<html><head><TITLE>\n  Sign &amp; in\n</TITLE></head></html>")
+	})
+
+	plainServer := httptest.NewServer(mux)
+	defer plainServer.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<title>Secure</title>")
+	}))
+	defer tlsServer.Close()
+
+	offsiteServer := httptest.NewServer(http.RedirectHandler("http://other.example/", http.StatusMovedPermanently))
+	defer offsiteServer.Close()
+
+	// An open port which does not speak HTTP.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			conn.Close()
+		}
+	}()
+
+	port := func(addr net.Addr) int {
+		return addr.(*net.TCPAddr).Port
+	}
+
+	plainPort, tlsPort, offsitePort := port(plainServer.Listener.Addr()), port(tlsServer.Listener.Addr()), port(offsiteServer.Listener.Addr())
+	ports := []int{plainPort, tlsPort, offsitePort, port(ln.Addr())}
+
+	hl := scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	res := scan.Run(&hl, ports, scan.Options{HTTP: true, NoDiscovery: true})
+	if len(res) != 1 || len(res[0].PortStates) != len(ports) {
+		t.Fatalf("expected 1 result with %d port states, got %v instead\n", len(ports), res)
+	}
+
+	expected := []*scan.HTTPInfo{
+		{
+			URL:        fmt.Sprintf("http://127.0.0.1:%d/", plainPort),
+			StatusCode: http.StatusOK,
+			Server:     "test-server/1.0",
+			Title:      "Sign & in",
+			Redirects:  []string{fmt.Sprintf("http://127.0.0.1:%d/login", plainPort)},
+		},
+		{
+			URL:        fmt.Sprintf("https://127.0.0.1:%d/", tlsPort),
+			StatusCode: http.StatusOK,
+			Title:      "Secure",
+		},
+		{
+			// Other hosts are not followed.
+			URL:        fmt.Sprintf("http://127.0.0.1:%d/", offsitePort),
+			StatusCode: http.StatusMovedPermanently,
+			Redirects:  []string{"http://other.example/"},
+		},
+		nil,
+	}
+
+	for i, ps := range res[0].PortStates {
+		info := ps.HTTP
+
+		if info != nil {
+			if info.ResponseTime <= 0 {
+				t.Errorf("expected a response time for port %d, got %s instead\n", ps.Port, info.ResponseTime)
+			}
+
+			// The response time changes on every run.
+			info.ResponseTime = 0
+		}
+
+		if !reflect.DeepEqual(info, expected[i]) {
+			t.Errorf("expected HTTP info %+v for port %d, got %+v instead\n", expected[i], ps.Port, info)
+		}
+	}
+}
+
+func TestHTTPInfoXML(t *testing.T) {
+	ps := scan.PortState{
+		Port:     80,
+		Protocol: scan.ProtocolTCP,
+		State:    scan.StateOpen,
+		HTTP: &scan.HTTPInfo{
+			URL:        "http://web/",
+			StatusCode: http.StatusOK,
+			Server:     "nginx",
+			Redirects:  []string{"http://web/login"},
+		},
+	}
+
+	data, err := xml.Marshal(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`<script id="http-title" output="Site doesn&#39;t have a title.&#xA;Requested resource was http://web/login">`,
+		`<script id="http-server-header" output="nginx">`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected the XML to contain %s, got %s instead\n", expected, data)
+		}
+	}
+}
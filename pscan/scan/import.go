@@ -0,0 +1,293 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Formats of the hosts files which can be imported and exported.
+const (
+	FormatNmapXML    = "nmap-xml"
+	FormatEtcHosts   = "etc-hosts"
+	FormatCSV        = "csv"
+	FormatKnownHosts = "ssh-known-hosts"
+)
+
+// SkippedEntry is an entry of an imported file which does not make a host of the list.
+type SkippedEntry struct {
+	// Line is the line of the entry in the file, 0 when the format has no lines to speak of.
+	Line   int
+	Entry  string
+	Reason string
+}
+
+// String converts the skipped entry to a human readable line.
+func (s SkippedEntry) String() string {
+	if s.Line == 0 {
+		return fmt.Sprintf("%s: %s", s.Entry, s.Reason)
+	}
+
+	return fmt.Sprintf("line %d: %s: %s", s.Line, s.Entry, s.Reason)
+}
+
+// ParseHosts reads the hosts of a file in one of the import formats.
+// The hosts come back as a list, together with the groups, tags and port overrides
+// of the formats carrying them, and the entries which cannot be used are reported as skipped.
+// A host found several times in the file is only kept once.
+func ParseHosts(r io.Reader, format string) (*HostsList, []SkippedEntry, error) {
+	p := &hostsParser{hl: &HostsList{}, seen: map[string]bool{}}
+
+	var err error
+	switch format {
+	case FormatNmapXML:
+		err = p.parseNmapXML(r)
+	case FormatEtcHosts:
+		err = p.parseLines(r, p.etcHostsLine)
+	case FormatCSV:
+		err = p.parseCSV(r)
+	case FormatKnownHosts:
+		err = p.parseLines(r, p.knownHostsLine)
+	default:
+		return nil, nil, fmt.Errorf("%w:%q, use one of %s, %s, %s or %s",
+			ErrInvalidFormat, format, FormatNmapXML, FormatEtcHosts, FormatCSV, FormatKnownHosts)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p.hl, p.skipped, nil
+}
+
+type hostsParser struct {
+	hl      *HostsList
+	seen    map[string]bool
+	skipped []SkippedEntry
+}
+
+// add keeps the first occurrence of a host, with its info if any.
+func (p *hostsParser) add(host string, info HostInfo) {
+	if p.seen[host] {
+		return
+	}
+
+	p.seen[host] = true
+	p.hl.Hosts = append(p.hl.Hosts, host)
+
+	if !info.IsZero() {
+		if p.hl.Info == nil {
+			p.hl.Info = map[string]HostInfo{}
+		}
+
+		p.hl.Info[host] = info
+	}
+}
+
+func (p *hostsParser) skip(line int, entry, reason string) {
+	p.skipped = append(p.skipped, SkippedEntry{Line: line, Entry: entry, Reason: reason})
+}
+
+// parseLines calls parse for every line which is neither blank nor a comment.
+func (p *hostsParser) parseLines(r io.Reader, parse func(n int, line string)) error {
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parse(n, line)
+	}
+
+	return scanner.Err()
+}
+
+// etcHostsLine reads a line of /etc/hosts, e.g. "10.0.0.1 db1.example.com db1 # primary".
+// The canonical name is imported, the aliases name the same host.
+func (p *hostsParser) etcHostsLine(n int, line string) {
+	line, _, _ = strings.Cut(line, "#")
+	fields := strings.Fields(line)
+
+	switch {
+	case !isAddr(fields[0]):
+		p.skip(n, fields[0], "not an IP address")
+	case len(fields) < 2:
+		p.skip(n, fields[0], "no host name")
+	default:
+		p.add(fields[1], HostInfo{})
+	}
+}
+
+// isAddr reports whether s is an IP address, zoned IPv6 addresses such as fe80::1%lo0 included.
+func isAddr(s string) bool {
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// knownHostsLine reads a line of an OpenSSH known_hosts file, e.g.
+// "db1.example.com,10.0.0.1 ssh-ed25519 AAAA...". The names and addresses separated by commas
+// are all imported, since they are only known to belong to the same host.
+func (p *hostsParser) knownHostsLine(n int, line string) {
+	fields := strings.Fields(line)
+
+	if strings.HasPrefix(fields[0], "@") {
+		p.skip(n, fields[0], "certificate authority and revoked keys are not hosts")
+		return
+	}
+
+	if strings.HasPrefix(fields[0], "|") {
+		p.skip(n, fields[0], "hashed host names cannot be read")
+		return
+	}
+
+	for _, host := range strings.Split(fields[0], ",") {
+		// Hosts on a non-standard port are written as [host]:port.
+		if strings.HasPrefix(host, "[") {
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+		}
+
+		if strings.ContainsAny(host, "*?!") {
+			p.skip(n, host, "wildcards and negations are not hosts")
+			continue
+		}
+
+		p.add(host, HostInfo{})
+	}
+}
+
+// parseCSV reads a CSV file with a host per row. The hosts are taken from the host column
+// when there is a header row, and from the first column otherwise.
+// The groups, tags and ports columns, written by the CSV export, are imported as well.
+func (p *hostsParser) parseCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	columns := map[string]int{"host": 0}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if first && isCSVHeader(record) {
+			columns = map[string]int{}
+			for i, name := range record {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+
+			continue
+		}
+
+		host := field(record, "host")
+		if host == "" {
+			line, _ := reader.FieldPos(0)
+			p.skip(line, strings.Join(record, ","), "no host")
+			continue
+		}
+
+		p.add(host, HostInfo{
+			Groups: strings.Fields(field(record, "groups")),
+			Tags:   strings.Fields(field(record, "tags")),
+			Ports:  field(record, "ports"),
+		})
+	}
+}
+
+// isCSVHeader reports whether the row is a header, that is it names a host column.
+func isCSVHeader(record []string) bool {
+	for _, name := range record {
+		if strings.EqualFold(strings.TrimSpace(name), "host") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nmapImportRun holds the parts of Nmap's XML output which name the hosts.
+type nmapImportRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"hostnames>hostname"`
+	} `xml:"host"`
+}
+
+// parseNmapXML reads the hosts of Nmap's XML output (-oX), pscan's XML output included.
+// Hosts are imported by the name they were scanned with, or by their address when they were
+// scanned by address. Hosts which are down are skipped.
+func (p *hostsParser) parseNmapXML(r io.Reader) error {
+	var run nmapImportRun
+
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return fmt.Errorf("cannot parse Nmap XML: %w", err)
+	}
+
+	for _, h := range run.Hosts {
+		host := ""
+
+		for _, name := range h.Hostnames {
+			if name.Type == "user" {
+				host = name.Name
+				break
+			}
+		}
+
+		if host == "" {
+			for _, a := range h.Addresses {
+				if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+					host = a.Addr
+					break
+				}
+			}
+		}
+
+		switch {
+		case host == "":
+			p.skip(0, "host without a name or an address", "nothing to scan")
+		case h.Status.State == "down":
+			p.skip(0, host, "host is down")
+		default:
+			p.add(host, HostInfo{})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package scan_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestParseHosts(t *testing.T) {
+	testCases := []struct {
+		name            string
+		format          string
+		input           string
+		expectedHosts   []string
+		expectedSkipped []scan.SkippedEntry
+	}{
+		{
+			name:   "EtcHosts",
+			format: scan.FormatEtcHosts,
+			input: `# local names
+127.0.0.1 localhost
+10.0.0.1	db1.example.com db1 # primary
+
+10.0.0.2 db1.example.com
+fe80::1%lo0 router
+db2.example.com
+10.0.0.3
+`,
+			expectedHosts: []string{"localhost", "db1.example.com", "router"},
+			expectedSkipped: []scan.SkippedEntry{
+				{Line: 7, Entry: "db2.example.com", Reason: "not an IP address"},
+				{Line: 8, Entry: "10.0.0.3", Reason: "no host name"},
+			},
+		},
+		{
+			name:   "KnownHosts",
+			format: scan.FormatKnownHosts,
+			input: `db1.example.com,10.0.0.1 ssh-ed25519 AAAA
+[git.example.com]:2222 ssh-rsa AAAA
+|1|c2FsdA==|aGFzaA== ssh-ed25519 AAAA
+@cert-authority *.example.com ssh-rsa AAAA
+*.internal,!bastion.internal ssh-rsa AAAA
+`,
+			expectedHosts: []string{"db1.example.com", "10.0.0.1", "git.example.com"},
+			expectedSkipped: []scan.SkippedEntry{
+				{Line: 3, Entry: "|1|c2FsdA==|aGFzaA==", Reason: "hashed host names cannot be read"},
+				{Line: 4, Entry: "@cert-authority", Reason: "certificate authority and revoked keys are not hosts"},
+				{Line: 5, Entry: "*.internal", Reason: "wildcards and negations are not hosts"},
+				{Line: 5, Entry: "!bastion.internal", Reason: "wildcards and negations are not hosts"},
+			},
+		},
+		{
+			name:          "CSVWithoutHeader",
+			format:        scan.FormatCSV,
+			input:         "host1,ignored\nhost2\nhost1\n",
+			expectedHosts: []string{"host1", "host2"},
+		},
+		{
+			name:          "CSVWithHeader",
+			format:        scan.FormatCSV,
+			input:         "name,Host\nweb,host1\ndb,\n",
+			expectedHosts: []string{"host1"},
+			expectedSkipped: []scan.SkippedEntry{
+				{Line: 3, Entry: "db,", Reason: "no host"},
+			},
+		},
+		{
+			name:   "NmapXML",
+			format: scan.FormatNmapXML,
+			input: `<?xml version="1.0"?>
+<nmaprun scanner="nmap">
+  <host><status state="up"/><address addr="10.0.0.1" addrtype="ipv4"/>
+    <hostnames><hostname name="web.example.com" type="user"/><hostname name="ptr.example.com" type="PTR"/></hostnames></host>
+  <host><status state="up"/><address addr="10.0.0.2" addrtype="ipv4"/><address addr="00:11:22:33:44:55" addrtype="mac"/></host>
+  <host><status state="down"/><address addr="10.0.0.3" addrtype="ipv4"/></host>
+</nmaprun>
+`,
+			expectedHosts: []string{"web.example.com", "10.0.0.2"},
+			expectedSkipped: []scan.SkippedEntry{
+				{Entry: "10.0.0.3", Reason: "host is down"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl, skipped, err := scan.ParseHosts(strings.NewReader(tc.input), tc.format)
+			if err != nil {
+				t.Fatalf("expected no error, got %q instead\n", err)
+			}
+
+			if !reflect.DeepEqual(hl.Hosts, tc.expectedHosts) {
+				t.Errorf("expected hosts %q, got %q instead\n", tc.expectedHosts, hl.Hosts)
+			}
+
+			if len(skipped) != len(tc.expectedSkipped) || (len(skipped) > 0 && !reflect.DeepEqual(skipped, tc.expectedSkipped)) {
+				t.Errorf("expected skipped entries %v, got %v instead\n", tc.expectedSkipped, skipped)
+			}
+		})
+	}
+}
+
+func TestParseHostsInvalidFormat(t *testing.T) {
+	_, _, err := scan.ParseHosts(strings.NewReader("host1\n"), "yaml")
+	if !errors.Is(err, scan.ErrInvalidFormat) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrInvalidFormat, err)
+	}
+}
+
+func TestExportHostsCSV(t *testing.T) {
+	hl := &scan.HostsList{}
+
+	for _, host := range []string{"host1", "10.0.0.0/30"} {
+		if err := hl.Add(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := scan.HostInfo{Groups: []string{"web", "prod"}, Tags: []string{"eu"}, Ports: "80,443"}
+	if err := hl.SetInfo("host1", info); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if _, err := scan.ExportHosts(context.Background(), &out, hl, scan.FormatCSV, scan.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "host,groups,tags,ports\n10.0.0.0/30,,,\nhost1,web prod,eu,\"80,443\"\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q instead\n", expected, out.String())
+	}
+
+	// The export is read back as the same list.
+	imported, _, err := scan.ParseHosts(&out, scan.FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(imported.Hosts, hl.Hosts) {
+		t.Errorf("expected hosts %q, got %q instead\n", hl.Hosts, imported.Hosts)
+	}
+
+	if !reflect.DeepEqual(imported.Info, hl.Info) {
+		t.Errorf("expected info %v, got %v instead\n", hl.Info, imported.Info)
+	}
+}
+
+func TestExportHostsNmapXML(t *testing.T) {
+	hl := &scan.HostsList{}
+
+	for _, host := range []string{"host1", "10.0.0.0/31"} {
+		if err := hl.Add(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if _, err := scan.ExportHosts(context.Background(), &out, hl, scan.FormatNmapXML, scan.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The patterns are expanded, and Nmap XML is read back host by host.
+	imported, skipped, err := scan.ParseHosts(&out, scan.FormatNmapXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"host1", "10.0.0.0", "10.0.0.1"}
+	if !reflect.DeepEqual(imported.Hosts, expected) {
+		t.Errorf("expected hosts %q, got %q instead\n", expected, imported.Hosts)
+	}
+
+	if len(skipped) != 0 {
+		t.Errorf("expected no skipped entries, got %v instead\n", skipped)
+	}
+}
+
+func TestExportHostsEtcHosts(t *testing.T) {
+	hl := &scan.HostsList{}
+
+	for _, host := range []string{"host1", "dual", "missing", "10.0.0.1"} {
+		if err := hl.Add(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := scan.Options{Resolver: fakeResolver{addrs: map[string][]string{
+		"host1": {"10.0.0.5"},
+		"dual":  {"::1", "127.0.0.1"},
+	}}}
+
+	var out bytes.Buffer
+	skipped, err := scan.ExportHosts(context.Background(), &out, hl, scan.FormatEtcHosts, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "127.0.0.1\tdual\n10.0.0.5\thost1\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q instead\n", expected, out.String())
+	}
+
+	expectedSkipped := []scan.SkippedEntry{
+		{Entry: "missing", Reason: "cannot be resolved"},
+		{Entry: "10.0.0.1", Reason: "an address needs no entry"},
+	}
+	if !reflect.DeepEqual(skipped, expectedSkipped) {
+		t.Errorf("expected skipped entries %v, got %v instead\n", expectedSkipped, skipped)
+	}
+}
+
+func TestExportHostsKnownHosts(t *testing.T) {
+	_, err := scan.ExportHosts(context.Background(), &bytes.Buffer{}, &scan.HostsList{}, scan.FormatKnownHosts, scan.Options{})
+	if !errors.Is(err, scan.ErrInvalidFormat) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrInvalidFormat, err)
+	}
+}
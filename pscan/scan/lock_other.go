@@ -0,0 +1,74 @@
+//go:build !unix
+
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// lockRetryInterval is how often lockFile checks whether the lock got released.
+	lockRetryInterval = 50 * time.Millisecond
+	// lockStaleAge is how old a lock file has to be to be considered left behind by a crashed process.
+	// The locks are only held while a file is updated, which takes far less.
+	lockStaleAge = 10 * time.Second
+	// lockTimeout is how long lockFile waits for the lock before giving up.
+	lockTimeout = 30 * time.Second
+)
+
+// lockFile takes an exclusive lock by creating the lock file at name, which fails
+// while another process holds it, and blocks until the lock is available.
+// A lock file older than lockStaleAge is removed, its process is most likely gone,
+// and lockFile gives up with ErrLocked after lockTimeout.
+// The returned function releases the lock by removing the file.
+func lockFile(name string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+		if err == nil {
+			f.Close()
+
+			return func() {
+				os.Remove(name)
+			}, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > lockStaleAge {
+			removeStaleLock(name, info)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w:%s", ErrLocked, name)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// removeStaleLock removes the stale lock file at name, unless another process replaced it
+// since it was stat'ed. The file is moved aside first, so that only one process takes it over,
+// and it is put back when it turns out to be the fresh lock of another process.
+func removeStaleLock(name string, stale os.FileInfo) {
+	aside := fmt.Sprintf("%s.stale%d", name, os.Getpid())
+
+	if err := os.Rename(name, aside); err != nil {
+		// Another process took it over, or its owner released it.
+		return
+	}
+
+	if info, err := os.Stat(aside); err == nil && !os.SameFile(info, stale) {
+		// Link fails rather than overwriting a lock created in the meantime.
+		os.Link(aside, name)
+	}
+
+	os.Remove(aside)
+}
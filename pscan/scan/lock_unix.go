@@ -0,0 +1,64 @@
+//go:build unix
+
+package scan
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on the lock file at name, creating it if needed,
+// and blocks until the lock is available. The returned function releases the lock
+// and removes the lock file, so that none is left next to the locked file.
+// The kernel releases the lock of a process which dies, a crash cannot leave a stale lock behind.
+func lockFile(name string) (func(), error) {
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		// The holder before us may have removed the file while we waited, the lock of
+		// a removed file guards nothing. Retry on the file which is at name now.
+		if locked, err := sameFile(f, name); err != nil || !locked {
+			f.Close()
+
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		return func() {
+			// Remove the file before releasing the lock, so that the waiting processes notice it.
+			os.Remove(name)
+			syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+			f.Close()
+		}, nil
+	}
+}
+
+// sameFile reports whether f is still the file at name.
+func sameFile(f *os.File, name string) (bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	current, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(info, current), nil
+}
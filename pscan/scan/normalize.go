@@ -0,0 +1,170 @@
+package scan
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// HostError reports why an entry of the hosts list is not a valid host.
+// It wraps ErrInvalidHost, ErrInvalidAddress or ErrInvalidHostName, so the kind of the problem
+// can be checked with errors.Is while errors.As gives access to the details.
+type HostError struct {
+	Host   string
+	Reason string
+	Err    error
+}
+
+func (e *HostError) Error() string {
+	return fmt.Sprintf("%s:%s: %s", e.Err, e.Host, e.Reason)
+}
+
+func (e *HostError) Unwrap() error {
+	return e.Err
+}
+
+func hostError(host string, err error, format string, args ...any) error {
+	return &HostError{Host: host, Reason: fmt.Sprintf(format, args...), Err: err}
+}
+
+// NormalizeHost validates an entry of the hosts list and returns its canonical form,
+// so that the same host is never listed twice under different spellings:
+//   - host names are lowercased and lose their trailing dot, their labels must be valid DNS labels
+//   - addresses and CIDR blocks are written in their canonical form, e.g. 2001:db8::1
+//   - URLs are reduced to their host, e.g. https://example.com:8443/login becomes example.com
+//
+// Other entries with a port, such as example.com:8080, are rejected, ports are set with the port overrides.
+// Patterns keep their syntax, see ExpandHost, but the hosts they expand into are validated as well.
+func NormalizeHost(entry string) (string, error) {
+	host := strings.TrimSpace(entry)
+	if host == "" {
+		return "", hostError(entry, ErrInvalidHost, "empty host")
+	}
+
+	// A URL only tells which host to scan, e.g. https://user@example.com:8443/login.
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		if i := strings.IndexAny(rest, "/?#"); i != -1 {
+			rest = rest[:i]
+		}
+
+		if i := strings.LastIndex(rest, "@"); i != -1 {
+			rest = rest[i+1:]
+		}
+
+		// The port of a URL belongs to its service, not to the host.
+		if h, _, err := net.SplitHostPort(rest); err == nil {
+			rest = h
+			if strings.Contains(h, ":") {
+				rest = "[" + h + "]"
+			}
+		}
+
+		host = rest
+	}
+
+	host, err := stripBrackets(entry, host)
+	if err != nil {
+		return "", err
+	}
+
+	if IsPattern(host) {
+		return normalizePattern(entry, host)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.String(), nil
+	}
+
+	if looksLikeAddress(host) {
+		return "", hostError(entry, ErrInvalidAddress, "not a valid IP address")
+	}
+
+	return normalizeName(entry, host)
+}
+
+// stripBrackets removes the brackets of an IPv6 address such as [2001:db8::1],
+// and rejects the entries carrying a port.
+func stripBrackets(entry, host string) (string, error) {
+	if strings.HasPrefix(host, "[") {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			return "", hostError(entry, ErrInvalidHost, "ports are not part of hosts, add %s with a port override", h)
+		}
+
+		if !strings.HasSuffix(host, "]") {
+			return "", hostError(entry, ErrInvalidAddress, "unbalanced brackets")
+		}
+
+		return host[1 : len(host)-1], nil
+	}
+
+	// A single colon separates a port, IPv6 addresses have at least two.
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.Contains(host[len(h)+1:], ":") {
+		return "", hostError(entry, ErrInvalidHost, "ports are not part of hosts, add %s with a port override", h)
+	}
+
+	return host, nil
+}
+
+// looksLikeAddress reports whether a host which is not a valid address was meant to be one,
+// such as 10.0.0.256 or 2001:db8:::1, rather than a host name.
+func looksLikeAddress(host string) bool {
+	if strings.Contains(host, ":") {
+		return true
+	}
+
+	return strings.Trim(host, "0123456789.") == ""
+}
+
+// normalizeName lowercases a host name and checks its labels, following RFC 1123.
+func normalizeName(entry, name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	if len(name) > 253 {
+		return "", hostError(entry, ErrInvalidHostName, "longer than 253 characters")
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		switch {
+		case label == "":
+			return "", hostError(entry, ErrInvalidHostName, "empty label")
+		case len(label) > 63:
+			return "", hostError(entry, ErrInvalidHostName, "label %s is longer than 63 characters", label)
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return "", hostError(entry, ErrInvalidHostName, "label %s starts or ends with a hyphen", label)
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return "", hostError(entry, ErrInvalidHostName, "label %s contains %q", label, c)
+			}
+		}
+	}
+
+	return name, nil
+}
+
+// normalizePattern writes a pattern in its canonical form and makes sure it expands into valid hosts.
+func normalizePattern(entry, pattern string) (string, error) {
+	pattern = strings.ToLower(pattern)
+
+	if prefix, err := netip.ParsePrefix(pattern); err == nil {
+		pattern = prefix.String()
+	}
+
+	hosts, err := ExpandHost(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	// Ranges and CIDR blocks expand into addresses, only the host name patterns need a check.
+	if strings.ContainsAny(pattern, "[{") {
+		for _, h := range hosts {
+			if _, err := normalizeName(entry, h); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return pattern, nil
+}
@@ -0,0 +1,156 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	testCases := []struct {
+		name        string
+		entry       string
+		expected    string
+		expectedErr error
+	}{
+		{"Name", "host1", "host1", nil},
+		{"UpperCaseName", "Web1.Example.COM", "web1.example.com", nil},
+		{"FullyQualifiedName", "example.com.", "example.com", nil},
+		{"Spaces", "  host1 ", "host1", nil},
+		{"IPv4", "10.0.0.1", "10.0.0.1", nil},
+		{"IPv6", "2001:DB8:0:0::1", "2001:db8::1", nil},
+		{"BracketedIPv6", "[::1]", "::1", nil},
+		{"URL", "https://user@Example.com/login?next=1", "example.com", nil},
+		{"URLWithPort", "http://example.com:8080/", "example.com", nil},
+		{"URLWithIPv6", "https://[2001:db8::1]:8443", "2001:db8::1", nil},
+		{"CIDR", "2001:DB8::/120", "2001:db8::/120", nil},
+		{"Range", "10.0.0.1-50", "10.0.0.1-50", nil},
+		{"NamePattern", "Web[01-02].example.com", "web[01-02].example.com", nil},
+		{"Empty", " ", "", scan.ErrInvalidHost},
+		{"Port", "example.com:8080", "", scan.ErrInvalidHost},
+		{"IPv6Port", "[::1]:22", "", scan.ErrInvalidHost},
+		{"InvalidIPv4", "10.0.0.256", "", scan.ErrInvalidAddress},
+		{"ShortIPv4", "10.0.1", "", scan.ErrInvalidAddress},
+		{"InvalidIPv6", "2001:db8:::1", "", scan.ErrInvalidAddress},
+		{"Underscore", "db_1", "", scan.ErrInvalidHostName},
+		{"EmptyLabel", "db..example.com", "", scan.ErrInvalidHostName},
+		{"Hyphen", "-db.example.com", "", scan.ErrInvalidHostName},
+		{"LongLabel", "a234567890123456789012345678901234567890123456789012345678901234.com", "", scan.ErrInvalidHostName},
+		{"InvalidNamePattern", "web{1,_2}", "", scan.ErrInvalidHostName},
+		{"InvalidPattern", "10.0.0.0/40", "", scan.ErrInvalidPattern},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			host, err := scan.NormalizeHost(tc.entry)
+
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected error %q, got %q instead\n", tc.expectedErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %q instead\n", err)
+			}
+
+			if host != tc.expected {
+				t.Errorf("expected host %q, got %q instead\n", tc.expected, host)
+			}
+		})
+	}
+}
+
+func TestHostError(t *testing.T) {
+	_, err := scan.NormalizeHost("db_1")
+
+	var hostErr *scan.HostError
+	if !errors.As(err, &hostErr) {
+		t.Fatalf("expected a *scan.HostError, got %T instead\n", err)
+	}
+
+	if hostErr.Host != "db_1" {
+		t.Errorf("expected host %q, got %q instead\n", "db_1", hostErr.Host)
+	}
+
+	expected := `invalid host name:db_1: label db_1 contains '_'`
+	if err.Error() != expected {
+		t.Errorf("expected error %q, got %q instead\n", expected, err)
+	}
+}
+
+func TestAddCanonical(t *testing.T) {
+	hl := &scan.HostsList{}
+
+	if err := hl.Add("Host1.Example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hl.Add("host1.example.com."); !errors.Is(err, scan.ErrExists) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrExists, err)
+	}
+
+	// The host is found by any of its spellings.
+	if err := hl.SetInfo("HOST1.example.com", scan.HostInfo{Tags: []string{"web"}}); err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if _, ok := hl.Info["host1.example.com"]; !ok {
+		t.Errorf("expected info of %q, got %v instead\n", "host1.example.com", hl.Info)
+	}
+
+	if err := hl.Remove("HOST1.EXAMPLE.COM"); err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if len(hl.Hosts) != 0 || len(hl.Info) != 0 {
+		t.Errorf("expected an empty list, got %q and %v instead\n", hl.Hosts, hl.Info)
+	}
+}
+
+func TestCheckHosts(t *testing.T) {
+	// A hand edited list, the entries do not go through Add.
+	hl := &scan.HostsList{Hosts: []string{
+		"db1",
+		"DB1",
+		"Web1",
+		"10.0.0.256",
+		"missing",
+		"app[1-2]",
+		"10.0.0.0/30",
+	}}
+
+	opts := scan.Options{Resolver: fakeResolver{addrs: map[string][]string{
+		"db1":  {"10.0.0.1"},
+		"web1": {"10.0.0.2"},
+		"app1": {"10.0.0.3"},
+	}}}
+
+	problems, err := scan.CheckHosts(context.Background(), hl, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	expectedHosts := []string{"DB1", "Web1", "10.0.0.256", "missing", "app2"}
+	expectedErrs := []error{scan.ErrDuplicateHost, scan.ErrNotCanonical, scan.ErrInvalidAddress, scan.ErrUnresolved, scan.ErrUnresolved}
+
+	hosts := []string{}
+	for _, p := range problems {
+		hosts = append(hosts, p.Host)
+	}
+
+	if !reflect.DeepEqual(hosts, expectedHosts) {
+		t.Fatalf("expected problems with %q, got %v instead\n", expectedHosts, problems)
+	}
+
+	for i, p := range problems {
+		if !errors.Is(p.Err, expectedErrs[i]) {
+			t.Errorf("expected error %q for %s, got %q instead\n", expectedErrs[i], p.Host, p.Err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package scan
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarshalText lets encoders such as JSON and XML write the state as "open" or "closed"
+// instead of a number.
+func (s State) MarshalText() ([]byte, error) {
+	if _, ok := stateNames[s]; !ok {
+		return nil, fmt.Errorf("%w:%d", ErrInvalidState, int(s))
+	}
+
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText is the counterpart of MarshalText, so that encoded results can be read back.
+func (s *State) UnmarshalText(text []byte) error {
+	for state, name := range stateNames {
+		if name == string(text) {
+			*s = state
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w:%q", ErrInvalidState, text)
+}
+
+// CSVHeader is the header row matching the records of Results.CSVRecords.
+func CSVHeader() []string {
+	return []string{
+		"host",
+		"address",
+		"found",
+		"status",
+		"port",
+		"protocol",
+		"state",
+		"reason",
+		"latency_ms",
+		"service",
+		"version",
+		"banner",
+		"tls_version",
+		"tls_cipher",
+		"tls_subject",
+		"tls_issuer",
+		"tls_sans",
+		"tls_not_after",
+		"http_url",
+		"http_status",
+		"http_server",
+		"http_title",
+		"http_redirects",
+		"http_response_ms",
+	}
+}
+
+// CSVRecords converts the results of a single host into CSV rows, one per port.
+// A host which is not found or down is represented by a single row without a port.
+// Every row has a column per CSVHeader column, the missing values are left empty.
+func (r Results) CSVRecords() [][]string {
+	header := CSVHeader()
+	found := strconv.FormatBool(!r.NotFound)
+	status := r.Status.String()
+
+	// newRecord pads the given leading columns up to the columns of the header.
+	newRecord := func(columns ...string) []string {
+		record := make([]string, len(header))
+		copy(record, columns)
+
+		return record
+	}
+
+	if r.NotFound || len(r.PortStates) == 0 {
+		return [][]string{newRecord(r.Host, r.Address, found, status)}
+	}
+
+	tlsColumn := slices.Index(header, "tls_version")
+	httpColumn := slices.Index(header, "http_url")
+
+	records := make([][]string, 0, len(r.PortStates))
+	for _, p := range r.PortStates {
+		record := newRecord(
+			r.Host,
+			r.Address,
+			found,
+			status,
+			strconv.Itoa(p.Port),
+			p.Protocol,
+			p.State.String(),
+			p.Reason,
+			milliseconds(p.Latency),
+			p.ServiceName(),
+			p.Version,
+			p.Banner,
+		)
+
+		if p.TLS != nil {
+			copy(record[tlsColumn:], []string{
+				p.TLS.Version,
+				p.TLS.CipherSuite,
+				p.TLS.Subject,
+				p.TLS.Issuer,
+				strings.Join(p.TLS.SANs, " "),
+				p.TLS.NotAfter.Format(time.RFC3339),
+			})
+		}
+
+		if p.HTTP != nil {
+			copy(record[httpColumn:], []string{
+				p.HTTP.URL,
+				strconv.Itoa(p.HTTP.StatusCode),
+				p.HTTP.Server,
+				p.HTTP.Title,
+				strings.Join(p.HTTP.Redirects, " "),
+				milliseconds(p.HTTP.ResponseTime),
+			})
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// milliseconds writes a duration in milliseconds with a microsecond precision.
+func milliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// The types below loosely follow the host section of Nmap's XML output (-oX),
+// so that the tools which already consume it can read pscan results as well.
+
+type xmlHost struct {
+	Status    xmlStatus     `xml:"status"`
+	Address   *xmlAddress   `xml:"address,omitempty"`
+	Hostnames []xmlHostname `xml:"hostnames>hostname"`
+	Ports     []PortState   `xml:"ports>port"`
+}
+
+type xmlStatus struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type xmlAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type xmlHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type xmlPort struct {
+	Protocol string      `xml:"protocol,attr"`
+	PortID   int         `xml:"portid,attr"`
+	State    xmlState    `xml:"state"`
+	Service  *xmlService `xml:"service,omitempty"`
+	Scripts  []xmlScript `xml:"script,omitempty"`
+}
+
+type xmlState struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+	// RTT is not part of Nmap's port state, it carries the latency in microseconds
+	// like the round trip times Nmap reports for hosts.
+	RTT int64 `xml:"rtt,attr"`
+}
+
+type xmlService struct {
+	Name    string `xml:"name,attr"`
+	Product string `xml:"product,attr,omitempty"`
+	Method  string `xml:"method,attr"`
+}
+
+// xmlScript mimics the output of Nmap's scripts, e.g. the banner script.
+type xmlScript struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+// MarshalXML writes the results as an Nmap <host> element.
+func (r Results) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	h := xmlHost{
+		// Nmap uses the "user-set" reason when the host discovery is skipped.
+		Status:    xmlStatus{State: "up", Reason: "user-set"},
+		Hostnames: []xmlHostname{{Name: r.Host, Type: "user"}},
+		Ports:     r.PortStates,
+	}
+
+	if r.Address != "" {
+		h.Address = &xmlAddress{Addr: r.Address, AddrType: "ipv4"}
+		if ip, err := netip.ParseAddr(r.Address); err == nil && !ip.Unmap().Is4() {
+			h.Address.AddrType = "ipv6"
+		}
+	}
+
+	// Nmap reports the names found by reverse DNS with the PTR type.
+	for _, name := range r.Names {
+		h.Hostnames = append(h.Hostnames, xmlHostname{Name: name, Type: "PTR"})
+	}
+
+	switch {
+	case r.NotFound:
+		h.Status = xmlStatus{State: "down", Reason: "unresolved"}
+	case r.Status != HostUnknown:
+		h.Status = xmlStatus{State: r.Status.String(), Reason: r.Reason}
+	}
+
+	start.Name = xml.Name{Local: "host"}
+	return e.EncodeElement(h, start)
+}
+
+// MarshalXML writes the port state as an Nmap <port> element.
+func (p PortState) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	xp := xmlPort{
+		Protocol: p.Protocol,
+		PortID:   p.Port,
+		State: xmlState{
+			State:  p.State.String(),
+			Reason: p.Reason,
+			RTT:    p.Latency.Microseconds(),
+		},
+	}
+
+	switch {
+	case p.Service != "":
+		// Nmap uses the "probed" method when the service is detected from its answers.
+		xp.Service = &xmlService{Name: p.Service, Product: p.Version, Method: "probed"}
+	case ServiceName(p.Port) != "":
+		// Nmap uses the "table" method when the service is guessed from the port number.
+		xp.Service = &xmlService{Name: ServiceName(p.Port), Method: "table"}
+	}
+
+	if p.Banner != "" {
+		xp.Scripts = append(xp.Scripts, xmlScript{ID: "banner", Output: p.Banner})
+	}
+
+	if p.TLS != nil {
+		xp.Scripts = append(xp.Scripts, xmlScript{ID: "ssl-cert", Output: p.TLS.String()})
+	}
+
+	if p.HTTP != nil {
+		xp.Scripts = append(xp.Scripts, httpScripts(*p.HTTP)...)
+	}
+
+	start.Name = xml.Name{Local: "port"}
+	return e.EncodeElement(xp, start)
+}
+
+// httpScripts mimics the output of Nmap's http-title and http-server-header scripts.
+func httpScripts(info HTTPInfo) []xmlScript {
+	title := info.Title
+	if title == "" {
+		title = "Site doesn't have a title."
+	}
+
+	if n := len(info.Redirects); n > 0 {
+		title += fmt.Sprintf("\nRequested resource was %s", info.Redirects[n-1])
+	}
+
+	scripts := []xmlScript{{ID: "http-title", Output: title}}
+
+	if info.Server != "" {
+		scripts = append(scripts, xmlScript{ID: "http-server-header", Output: info.Server})
+	}
+
+	return scripts
+}
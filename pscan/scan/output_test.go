@@ -0,0 +1,70 @@
+package scan_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestResultsJSON(t *testing.T) {
+	res := []scan.Results{
+		{
+			Host: "host1",
+			PortStates: []scan.PortState{
+				{
+					Port:     22,
+					Protocol: scan.ProtocolTCP,
+					State:    scan.StateOpen,
+					Reason:   "syn-ack",
+					Latency:  time.Millisecond,
+				},
+				{
+					Port:     53,
+					Protocol: scan.ProtocolUDP,
+					State:    scan.StateOpenFiltered,
+					Reason:   "no-response",
+					Latency:  time.Second,
+				},
+			},
+		},
+		{
+			Host:     "host2",
+			NotFound: true,
+		},
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"host":"host1","notFound":false,"ports":[` +
+		`{"port":22,"protocol":"tcp","state":"open","reason":"syn-ack","latency":1000000},` +
+		`{"port":53,"protocol":"udp","state":"open|filtered","reason":"no-response","latency":1000000000}]},` +
+		`{"host":"host2","notFound":true}]`
+	if string(data) != expected {
+		t.Errorf("expected JSON %s, got %s instead\n", expected, data)
+	}
+
+	var decoded []scan.Results
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected no error while decoding, got %q instead\n", err)
+	}
+
+	if decoded[0].PortStates[0].State != scan.StateOpen ||
+		decoded[0].PortStates[1].State != scan.StateOpenFiltered {
+		t.Errorf("expected decoded states to match, got %+v instead\n", decoded[0].PortStates)
+	}
+}
+
+func TestResultsJSONInvalidState(t *testing.T) {
+	var ps scan.PortState
+
+	err := json.Unmarshal([]byte(`{"port":22,"state":"ajar"}`), &ps)
+	if !errors.Is(err, scan.ErrInvalidState) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrInvalidState, err)
+	}
+}
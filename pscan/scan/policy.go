@@ -0,0 +1,381 @@
+package scan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity ranks how serious a policy violation is.
+type Severity int
+
+const (
+	// SeverityDefault leaves the severity of a rule's violations to their kind.
+	SeverityDefault Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityDefault:  "default",
+	SeverityLow:      "low",
+	SeverityMedium:   "medium",
+	SeverityHigh:     "high",
+	SeverityCritical: "critical",
+}
+
+// String converts the severity to its name.
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity converts the name of a severity, low, medium, high or critical, into a Severity.
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == name && s != SeverityDefault {
+			return s, nil
+		}
+	}
+
+	return SeverityDefault, fmt.Errorf("%w:%q, use one of low, medium, high or critical", ErrInvalidSeverity, name)
+}
+
+// MarshalText lets encoders write the severity by its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	if _, ok := severityNames[s]; !ok {
+		return nil, fmt.Errorf("%w:%d", ErrInvalidSeverity, int(s))
+	}
+
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText is the counterpart of MarshalText.
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = severity
+	return nil
+}
+
+// ViolationKind is the way an open or closed port breaks a rule.
+type ViolationKind int
+
+const (
+	// PortForbidden is an open port among the forbidden ones.
+	PortForbidden ViolationKind = iota
+	// PortNotAllowed is an open port outside of the allowed ones.
+	PortNotAllowed
+	// PortMissing is a required port which is not open.
+	PortMissing
+)
+
+var violationKindNames = map[ViolationKind]string{
+	PortForbidden:  "forbidden",
+	PortNotAllowed: "not-allowed",
+	PortMissing:    "missing",
+}
+
+// defaultSeverities are used for the violations of the rules which do not set a severity.
+var defaultSeverities = map[ViolationKind]Severity{
+	PortForbidden:  SeverityHigh,
+	PortNotAllowed: SeverityMedium,
+	PortMissing:    SeverityMedium,
+}
+
+// String converts the violation kind to its name.
+func (k ViolationKind) String() string {
+	return violationKindNames[k]
+}
+
+// MarshalText lets encoders write the violation kind by its name.
+func (k ViolationKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText is the counterpart of MarshalText.
+func (k *ViolationKind) UnmarshalText(text []byte) error {
+	for kind, name := range violationKindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w:%q", ErrInvalidPolicy, text)
+}
+
+// Policy is the expected state of the ports of the hosts, see LoadPolicy for its file.
+type Policy struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule is the expectation on the ports of the hosts it selects.
+// The ports use the syntax of ParsePorts, and at least one of Allowed, Required and Forbidden is set.
+type Rule struct {
+	// Name identifies the rule in the violations, it defaults to the position of the rule.
+	Name string `yaml:"name" json:"name"`
+	// Hosts, Groups and Tags select the hosts the rule applies to, a host matching any of them is selected.
+	// A rule without them applies to every host. Hosts can be patterns, see ExpandHost,
+	// and match the scanned addresses as well.
+	Hosts  []string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+	Tags   []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Allowed are the only ports which may be open, any port may be open when it is empty.
+	Allowed string `yaml:"allowed,omitempty" json:"allowed,omitempty"`
+	// Required are the ports which must be open.
+	Required string `yaml:"required,omitempty" json:"required,omitempty"`
+	// Forbidden are the ports which must not be open.
+	Forbidden string `yaml:"forbidden,omitempty" json:"forbidden,omitempty"`
+	// Severity is the severity of every violation of the rule, it depends on their kind by default:
+	// high for the forbidden ports and medium for the others.
+	Severity Severity `yaml:"severity,omitempty" json:"severity,omitempty"`
+
+	allowed, required, forbidden []int
+	hosts                        map[string]bool
+}
+
+// LoadPolicy reads a policy file, YAML or JSON, such as:
+//
+//	rules:
+//	  - name: prod
+//	    groups: [prod]
+//	    allowed: 22,443
+//	    required: 443
+//	  - name: no telnet
+//	    forbidden: telnet
+//	    severity: critical
+//
+// Every rule is validated, so that a typo fails the audit instead of passing it.
+func LoadPolicy(policyFile string) (*Policy, error) {
+	data, err := os.ReadFile(policyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Policy{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w:%s: %w", ErrInvalidPolicy, policyFile, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%w:%s: %w", ErrInvalidPolicy, policyFile, err)
+	}
+
+	return p, nil
+}
+
+// Validate checks the rules and prepares them for Audit, LoadPolicy calls it for the policies it reads.
+func (p *Policy) Validate() error {
+	if len(p.Rules) == 0 {
+		return errors.New("no rules")
+	}
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if r.Allowed == "" && r.Required == "" && r.Forbidden == "" {
+			return fmt.Errorf("%s: set at least one of allowed, required or forbidden", r.Name)
+		}
+
+		for _, spec := range []struct {
+			ports *[]int
+			text  string
+		}{{&r.allowed, r.Allowed}, {&r.required, r.Required}, {&r.forbidden, r.Forbidden}} {
+			if spec.text == "" {
+				continue
+			}
+
+			var err error
+			if *spec.ports, err = ParsePorts(spec.text); err != nil {
+				return fmt.Errorf("%s: %w", r.Name, err)
+			}
+		}
+
+		r.hosts = map[string]bool{}
+		for _, entry := range r.Hosts {
+			hosts, err := ExpandHost(entry)
+			if err != nil {
+				return fmt.Errorf("%s: %w", r.Name, err)
+			}
+
+			for _, h := range hosts {
+				r.hosts[h] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// Ports returns the required and forbidden ports of the rules, which an audit has to scan.
+func (p *Policy) Ports() []int {
+	ports := []int{}
+	for _, r := range p.Rules {
+		ports = append(ports, r.required...)
+		ports = append(ports, r.forbidden...)
+	}
+
+	slices.Sort(ports)
+	return slices.Compact(ports)
+}
+
+// Violation is an open or a closed port of a host which breaks a rule of the policy.
+type Violation struct {
+	Rule     string        `json:"rule"`
+	Host     string        `json:"host"`
+	Address  string        `json:"address,omitempty"`
+	Port     int           `json:"port"`
+	Protocol string        `json:"protocol,omitempty"`
+	Kind     ViolationKind `json:"kind"`
+	Severity Severity      `json:"severity"`
+	// State explains why a required port is missing, e.g. "closed", "filtered" or "host down".
+	State string `json:"state,omitempty"`
+}
+
+// String converts the violation to a human readable line.
+func (v Violation) String() string {
+	port := fmt.Sprintf("%d", v.Port)
+	if v.Protocol != "" {
+		port += "/" + v.Protocol
+	}
+
+	switch v.Kind {
+	case PortForbidden:
+		return fmt.Sprintf("%s: port %s is open, forbidden by %s", v.Host, port, v.Rule)
+	case PortNotAllowed:
+		return fmt.Sprintf("%s: port %s is open, not allowed by %s", v.Host, port, v.Rule)
+	default:
+		return fmt.Sprintf("%s: port %s is not open (%s), required by %s", v.Host, port, v.State, v.Rule)
+	}
+}
+
+// Audit checks the results of a scan of the hosts list against a validated policy,
+// and returns the violations in the order of the results, then of the rules and the ports.
+// The groups and tags of the hosts come from the list, the results of a pattern
+// have the groups and tags of its entry.
+// Only the scanned ports can be checked, so Allowed only catches the open ports the scan looked at.
+func (p *Policy) Audit(hl *HostsList, results []Results) []Violation {
+	info := targetInfo(hl)
+	violations := []Violation{}
+
+	for _, r := range results {
+		for _, rule := range p.Rules {
+			if rule.applies(r, info[r.Host]) {
+				violations = append(violations, rule.check(r)...)
+			}
+		}
+	}
+
+	return violations
+}
+
+// targetInfo maps the targets of the hosts list to the info of their entries.
+func targetInfo(hl *HostsList) map[string]HostInfo {
+	info := map[string]HostInfo{}
+
+	for _, entry := range hl.Hosts {
+		hosts, err := ExpandHost(entry)
+		if err != nil {
+			hosts = []string{entry}
+		}
+
+		for _, h := range hosts {
+			if _, ok := info[h]; !ok {
+				info[h] = hl.Info[entry]
+			}
+		}
+	}
+
+	return info
+}
+
+// applies reports whether the rule selects the host of the results, by its name, its address, or its info.
+func (r Rule) applies(res Results, info HostInfo) bool {
+	if len(r.Hosts) == 0 && len(r.Groups) == 0 && len(r.Tags) == 0 {
+		return true
+	}
+
+	if r.hosts[res.Host] || (res.Address != "" && r.hosts[res.Address]) {
+		return true
+	}
+
+	return containsAny(info.Groups, r.Groups) || containsAny(info.Tags, r.Tags)
+}
+
+func (r Rule) check(res Results) []Violation {
+	violations := []Violation{}
+
+	add := func(kind ViolationKind, port int, protocol, state string) {
+		severity := r.Severity
+		if severity == SeverityDefault {
+			severity = defaultSeverities[kind]
+		}
+
+		violations = append(violations, Violation{
+			Rule:     r.Name,
+			Host:     res.Host,
+			Address:  res.Address,
+			Port:     port,
+			Protocol: protocol,
+			Kind:     kind,
+			Severity: severity,
+			State:    state,
+		})
+	}
+
+	states := map[int]PortState{}
+	for _, ps := range res.PortStates {
+		states[ps.Port] = ps
+
+		if ps.State != StateOpen {
+			continue
+		}
+
+		switch {
+		case slices.Contains(r.forbidden, ps.Port):
+			add(PortForbidden, ps.Port, ps.Protocol, "")
+		case len(r.allowed) > 0 && !slices.Contains(r.allowed, ps.Port):
+			add(PortNotAllowed, ps.Port, ps.Protocol, "")
+		}
+	}
+
+	for _, port := range r.required {
+		ps, scanned := states[port]
+
+		switch {
+		case res.NotFound:
+			add(PortMissing, port, "", "host not found")
+		case res.Status == HostDown:
+			add(PortMissing, port, "", "host down")
+		case !scanned:
+			// A port override of the host left the port out of the scan.
+			add(PortMissing, port, "", "not scanned")
+		case ps.State != StateOpen:
+			add(PortMissing, port, ps.Protocol, ps.State.String())
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,140 @@
+package scan_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func writePolicy(t *testing.T, data string) string {
+	t.Helper()
+
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return policyFile
+}
+
+func TestLoadPolicyInvalid(t *testing.T) {
+	testCases := []struct {
+		name        string
+		data        string
+		expectedErr error
+	}{
+		{"Empty", "", scan.ErrInvalidPolicy},
+		{"UnknownField", "rules:\n  - allowd: 22\n", scan.ErrInvalidPolicy},
+		{"NoPorts", "rules:\n  - groups: [prod]\n", scan.ErrInvalidPolicy},
+		{"InvalidPorts", "rules:\n  - allowed: 22,nope\n", scan.ErrUnknownService},
+		{"InvalidSeverity", "rules:\n  - allowed: 22\n    severity: urgent\n", scan.ErrInvalidSeverity},
+		{"InvalidHosts", "rules:\n  - hosts: [10.0.0.0/40]\n    allowed: 22\n", scan.ErrInvalidPattern},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := scan.LoadPolicy(writePolicy(t, tc.data))
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %q, got %q instead\n", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestPolicyAudit(t *testing.T) {
+	policy, err := scan.LoadPolicy(writePolicy(t, `rules:
+  - name: prod
+    groups: [prod]
+    allowed: 22,443
+    required: 443
+  - hosts: [10.0.0.0/30]
+    forbidden: telnet
+    severity: critical
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPorts := []int{23, 443}
+	if !reflect.DeepEqual(policy.Ports(), expectedPorts) {
+		t.Errorf("expected ports %v, got %v instead\n", expectedPorts, policy.Ports())
+	}
+
+	hl := &scan.HostsList{}
+	for _, host := range []string{"web1", "db1", "down1", "other"} {
+		if err := hl.Add(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, host := range []string{"web1", "db1", "down1"} {
+		if err := hl.SetInfo(host, scan.HostInfo{Groups: []string{"prod"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	open := func(port int) scan.PortState {
+		return scan.PortState{Port: port, Protocol: scan.ProtocolTCP, State: scan.StateOpen}
+	}
+
+	closed := func(port int) scan.PortState {
+		return scan.PortState{Port: port, Protocol: scan.ProtocolTCP, State: scan.StateClosed}
+	}
+
+	results := []scan.Results{
+		{Host: "web1", Address: "10.0.0.1", PortStates: []scan.PortState{open(22), open(23), open(443)}},
+		{Host: "db1", Address: "10.0.1.1", PortStates: []scan.PortState{open(22), closed(443), open(5432)}},
+		{Host: "down1", Status: scan.HostDown},
+		// Not selected by any rule but the ones for every host, and there are none.
+		{Host: "other", Address: "10.0.2.1", PortStates: []scan.PortState{open(23)}},
+	}
+
+	expected := []scan.Violation{
+		{Rule: "prod", Host: "web1", Address: "10.0.0.1", Port: 23, Protocol: "tcp", Kind: scan.PortNotAllowed, Severity: scan.SeverityMedium},
+		{Rule: "rule 2", Host: "web1", Address: "10.0.0.1", Port: 23, Protocol: "tcp", Kind: scan.PortForbidden, Severity: scan.SeverityCritical},
+		{Rule: "prod", Host: "db1", Address: "10.0.1.1", Port: 5432, Protocol: "tcp", Kind: scan.PortNotAllowed, Severity: scan.SeverityMedium},
+		{Rule: "prod", Host: "db1", Address: "10.0.1.1", Port: 443, Protocol: "tcp", Kind: scan.PortMissing, Severity: scan.SeverityMedium, State: "closed"},
+		{Rule: "prod", Host: "down1", Port: 443, Kind: scan.PortMissing, Severity: scan.SeverityMedium, State: "host down"},
+	}
+
+	violations := policy.Audit(hl, results)
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("expected violations %v, got %v instead\n", expected, violations)
+	}
+}
+
+func TestViolationString(t *testing.T) {
+	testCases := []struct {
+		name      string
+		violation scan.Violation
+		expected  string
+	}{
+		{
+			"Forbidden",
+			scan.Violation{Rule: "prod", Host: "web1", Port: 23, Protocol: "tcp", Kind: scan.PortForbidden},
+			"web1: port 23/tcp is open, forbidden by prod",
+		},
+		{
+			"NotAllowed",
+			scan.Violation{Rule: "prod", Host: "web1", Port: 8080, Protocol: "tcp", Kind: scan.PortNotAllowed},
+			"web1: port 8080/tcp is open, not allowed by prod",
+		},
+		{
+			"Missing",
+			scan.Violation{Rule: "prod", Host: "web1", Port: 443, Kind: scan.PortMissing, State: "host down"},
+			"web1: port 443 is not open (host down), required by prod",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.violation.String() != tc.expected {
+				t.Errorf("expected %q, got %q instead\n", tc.expected, tc.violation.String())
+			}
+		})
+	}
+}
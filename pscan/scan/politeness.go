@@ -0,0 +1,135 @@
+package scan
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// probePort scans a single port while keeping the scan polite: every probe waits for
+// the rate limiter and a random jitter, and inconclusive answers are retried.
+func probePort(ctx context.Context, host string, port int, opts Options, limiter *rateLimiter) (PortState, error) {
+	var ps PortState
+
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if err := limiter.wait(ctx); err != nil {
+			return ps, err
+		}
+
+		if opts.Jitter > 0 {
+			if err := sleep(ctx, time.Duration(rand.Int63n(int64(opts.Jitter)))); err != nil {
+				return ps, err
+			}
+		}
+
+		var err error
+		if ps, err = scanPort(ctx, host, port, opts); err != nil {
+			return ps, err
+		}
+
+		if conclusive(ps.State) {
+			break
+		}
+	}
+
+	return ps, nil
+}
+
+// conclusive reports whether a state is worth keeping without retrying the probe.
+// A filtered port may as well be a lost packet, and an error may be a transient one.
+func conclusive(s State) bool {
+	return s == StateOpen || s == StateClosed
+}
+
+// sleep waits for d, returning early with the context error if ctx ends first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimiter spaces the probes evenly so that no more than rate probes are sent per second.
+// A nil rateLimiter does not limit anything.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{interval: time.Second / time.Duration(rate)}
+}
+
+// wait blocks until the next probe is allowed to go.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	// Reserve the next free slot, then sleep outside the lock until it comes.
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// hostSlots caps the number of concurrent probes per host.
+// A nil hostSlots does not limit anything.
+type hostSlots []chan struct{}
+
+func newHostSlots(hosts, perHost int) hostSlots {
+	if perHost <= 0 {
+		return nil
+	}
+
+	slots := make(hostSlots, hosts)
+	for i := range slots {
+		slots[i] = make(chan struct{}, perHost)
+	}
+
+	return slots
+}
+
+// acquire blocks until the host has a free slot.
+func (s hostSlots) acquire(ctx context.Context, host int) error {
+	if s == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case s[host] <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s hostSlots) release(host int) {
+	if s == nil {
+		return
+	}
+
+	<-s[host]
+}
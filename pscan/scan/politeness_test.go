@@ -0,0 +1,148 @@
+package scan_test
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// closedPorts returns ports on localhost which nothing listens on.
+func closedPorts(t *testing.T, n int) []int {
+	t.Helper()
+
+	ports := []int{}
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ports = append(ports, ln.Addr().(*net.TCPAddr).Port)
+		ln.Close()
+	}
+
+	return ports
+}
+
+func TestRunRate(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+
+	ports := closedPorts(t, 10)
+
+	// 10 probes at 50 per second need at least 9 intervals of 20ms.
+	start := time.Now()
+	res := scan.Run(&hl, ports, scan.Options{Rate: 50})
+	elapsed := time.Since(start)
+
+	if len(res) != 1 || len(res[0].PortStates) != len(ports) {
+		t.Fatalf("expected 1 result with %d port states, got %v instead\n", len(ports), res)
+	}
+
+	if elapsed < 180*time.Millisecond {
+		t.Errorf("expected the scan to take at least 180ms, took %s instead\n", elapsed)
+	}
+}
+
+func TestRunMaxPerHost(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+
+	// Every listener stays silent, so each banner grab holds its connection
+	// for two timeouts: one waiting for a greeting and one for the fallback probe.
+	ports := []int{}
+	for i := 0; i < 6; i++ {
+		ports = append(ports, serve(t, func(conn net.Conn) {
+			bufio.NewReader(conn).ReadString(0)
+		}))
+	}
+
+	// 6 probes, 2 at a time, need at least 3 rounds of 100ms.
+	opts := scan.Options{Workers: 6, MaxPerHost: 2, Banner: true, Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	scan.Run(&hl, ports, opts)
+	elapsed := time.Since(start)
+
+	if elapsed < 300*time.Millisecond {
+		t.Errorf("expected the scan to take at least 300ms, took %s instead\n", elapsed)
+	}
+}
+
+func TestRunRandomizeKeepsOrder(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+
+	ports := closedPorts(t, 20)
+
+	res := scan.Run(&hl, ports, scan.Options{Randomize: true, Jitter: time.Millisecond})
+
+	if len(res) != 1 || len(res[0].PortStates) != len(ports) {
+		t.Fatalf("expected 1 result with %d port states, got %v instead\n", len(ports), res)
+	}
+
+	for i, ps := range res[0].PortStates {
+		if ps.Port != ports[i] {
+			t.Errorf("expected port %d at index %d, got %d instead\n", ports[i], i, ps.Port)
+		}
+	}
+}
+
+func TestRunRetriesAndTimeout(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("127.0.0.1")
+
+	// A silent UDP server never answers, so every attempt times out and gets retried.
+	silent, err := net.ListenPacket("udp", net.JoinHostPort("127.0.0.1", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer silent.Close()
+
+	var mu sync.Mutex
+	received := 0
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			if _, _, err := silent.ReadFrom(buf); err != nil {
+				return
+			}
+
+			mu.Lock()
+			received++
+			mu.Unlock()
+		}
+	}()
+
+	opts := scan.Options{UDP: true, Retries: 2, Timeout: 50 * time.Millisecond}
+	res := scan.Run(&hl, []int{silent.LocalAddr().(*net.UDPAddr).Port}, opts)
+
+	if len(res) != 1 || len(res[0].PortStates) != 1 {
+		t.Fatalf("expected 1 result with 1 port state, got %v instead\n", res)
+	}
+
+	ps := res[0].PortStates[0]
+	if ps.State != scan.StateOpenFiltered {
+		t.Errorf("expected port to be %s, got %s instead\n", scan.StateOpenFiltered, ps.State)
+	}
+
+	if ps.Latency > 500*time.Millisecond {
+		t.Errorf("expected the probe to time out after 50ms, took %s instead\n", ps.Latency)
+	}
+
+	// Give the server a moment to count the last datagram.
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if received != 3 {
+		t.Errorf("expected 3 probes, 1 and 2 retries, got %d instead\n", received)
+	}
+}
@@ -0,0 +1,195 @@
+package scan
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinPort = 1
+	MaxPort = 65535
+)
+
+// presets are named port specifications which can be used in place of a port list.
+// The top lists follow the most frequently open TCP ports reported by Nmap.
+var presets = map[string]string{
+	"top10": "21-23,25,80,110,139,443,445,3389",
+	"top100": "7,9,13,21-23,25-26,37,53,79-81,88,106,110-111,113,119,135,139,143-144," +
+		"179,199,389,427,443-445,465,513-515,543-544,548,554,587,631,646,873,990,993,995," +
+		"1025-1029,1110,1433,1720,1723,1755,1900,2000-2001,2049,2121,2717,3000,3128,3306," +
+		"3389,3986,4899,5000,5009,5051,5060,5101,5190,5357,5432,5631,5666,5800,5900," +
+		"6000-6001,6646,7070,8000,8008-8009,8080-8081,8443,8888,9100,9999-10000,32768," +
+		"49152-49157",
+	"all": "1-65535",
+}
+
+//go:embed services.txt
+var servicesTable string
+
+// services maps the service names and aliases in the embedded table to their ports.
+// servicePorts does the opposite, keeping the primary name of each port.
+var services, servicePorts = loadServices(servicesTable)
+
+func loadServices(table string) (map[string]int, map[int]string) {
+	byName := map[string]int{}
+	byPort := map[int]string{}
+
+	scanner := bufio.NewScanner(strings.NewReader(table))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		portStr, _, _ := strings.Cut(fields[1], "/")
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			// The table is embedded, a broken line is a programming error.
+			panic(fmt.Sprintf("scan: invalid services table line %q", scanner.Text()))
+		}
+
+		if _, ok := byPort[port]; !ok {
+			byPort[port] = fields[0]
+		}
+
+		for _, name := range append(fields[:1], fields[2:]...) {
+			byName[name] = port
+		}
+	}
+
+	return byName, byPort
+}
+
+// ServiceName returns the well known service name of the given port,
+// or an empty string if the port is not in the services table.
+func ServiceName(port int) string {
+	return servicePorts[port]
+}
+
+// ParsePorts parses a port specification into a sorted list of unique ports.
+// The specification is a comma separated list of:
+//   - single ports, e.g. 22
+//   - port ranges, e.g. 1-1024
+//   - service names, e.g. ssh,http,https
+//   - presets, e.g. top10, top100 or all
+//
+// Any of the above can be prefixed with ! to exclude it from the list, e.g. 1-1024,!25.
+func ParsePorts(spec string) ([]int, error) {
+	include := map[int]bool{}
+	exclude := map[int]bool{}
+
+	if err := parsePortSpec(spec, include, exclude); err != nil {
+		return nil, err
+	}
+
+	ports := make([]int, 0, len(include))
+	for p := range include {
+		if !exclude[p] {
+			ports = append(ports, p)
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("%w:%q does not select any port", ErrInvalidPortSpec, spec)
+	}
+
+	sort.Ints(ports)
+	return ports, nil
+}
+
+func parsePortSpec(spec string, include, exclude map[int]bool) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+
+		target := include
+		if strings.HasPrefix(item, "!") {
+			target = exclude
+			item = strings.TrimSpace(item[1:])
+		}
+
+		if item == "" {
+			return fmt.Errorf("%w:%q contains an empty item", ErrInvalidPortSpec, spec)
+		}
+
+		// Presets are specifications themselves, mark their ports on the same target.
+		if preset, ok := presets[strings.ToLower(item)]; ok {
+			if err := parsePortSpec(preset, target, target); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		from, to, err := parsePortItem(item)
+		if err != nil {
+			return err
+		}
+
+		for p := from; p <= to; p++ {
+			target[p] = true
+		}
+	}
+
+	return nil
+}
+
+// parsePortItem parses a single port, a range or a service name into an inclusive port range.
+func parsePortItem(item string) (int, int, error) {
+	if first, last, ok := strings.Cut(item, "-"); ok && isNumber(first) {
+		from, err := parsePort(first)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		to, err := parsePort(last)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if from > to {
+			return 0, 0, fmt.Errorf("%w:range %q is reversed", ErrInvalidPortSpec, item)
+		}
+
+		return from, to, nil
+	}
+
+	if isNumber(item) {
+		p, err := parsePort(item)
+		return p, p, err
+	}
+
+	p, ok := services[strings.ToLower(item)]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w:%s", ErrUnknownService, item)
+	}
+
+	return p, p, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || p < MinPort || p > MaxPort {
+		return 0, fmt.Errorf("%w:%s (must be between %d and %d)", ErrInvalidPort, s, MinPort, MaxPort)
+	}
+
+	return p, nil
+}
+
+func isNumber(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package scan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestParsePorts(t *testing.T) {
+	testCases := []struct {
+		name          string
+		spec          string
+		expectedPorts []int
+		expectedError error
+	}{
+		{"SinglePort", "22", []int{22}, nil},
+		{"List", "443, 22,80", []int{22, 80, 443}, nil},
+		{"Duplicates", "22,22,20-22", []int{20, 21, 22}, nil},
+		{"Range", "1-5", []int{1, 2, 3, 4, 5}, nil},
+		{"Exclusion", "1-5,!3", []int{1, 2, 4, 5}, nil},
+		{"ExcludedRange", "!2-4,1-5", []int{1, 5}, nil},
+		{"Services", "ssh,HTTP,https", []int{22, 80, 443}, nil},
+		{"ServiceAlias", "postgres", []int{5432}, nil},
+		{"DashedService", "http-alt", []int{8080}, nil},
+		{"ExcludedService", "20-25,!ssh", []int{20, 21, 23, 24, 25}, nil},
+		{"Preset", "top10,!21-23", []int{25, 80, 110, 139, 443, 445, 3389}, nil},
+		{"PortZero", "0", nil, scan.ErrInvalidPort},
+		{"PortTooBig", "65536", nil, scan.ErrInvalidPort},
+		{"RangeTooBig", "65000-70000", nil, scan.ErrInvalidPort},
+		{"ReversedRange", "10-1", nil, scan.ErrInvalidPortSpec},
+		{"EmptyItem", "22,,80", nil, scan.ErrInvalidPortSpec},
+		{"NothingSelected", "22,!ssh", nil, scan.ErrInvalidPortSpec},
+		{"UnknownService", "gopherhole", nil, scan.ErrUnknownService},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ports, err := scan.ParsePorts(tc.spec)
+
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Errorf("expected error %q, got %q instead\n", tc.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %q instead\n", err)
+			}
+
+			if !slices.Equal(ports, tc.expectedPorts) {
+				t.Errorf("expected ports %v, got %v instead\n", tc.expectedPorts, ports)
+			}
+		})
+	}
+}
+
+func TestParsePortsTop100(t *testing.T) {
+	ports, err := scan.ParsePorts("top100")
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if len(ports) != 100 {
+		t.Errorf("expected 100 ports, got %d instead\n", len(ports))
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	if name := scan.ServiceName(22); name != "ssh" {
+		t.Errorf("expected service %q for port 22, got %q instead\n", "ssh", name)
+	}
+
+	if name := scan.ServiceName(1); name != "" {
+		t.Errorf("expected no service for port 1, got %q instead\n", name)
+	}
+}
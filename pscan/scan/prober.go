@@ -0,0 +1,124 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Prober probes a single port of an address and reports its state.
+// The returned error is only set when ctx ends before the probe completes,
+// in which case the port state is unknown.
+type Prober interface {
+	Probe(ctx context.Context, address string, port int, opts Options) (PortState, error)
+}
+
+// ProberFunc lets an ordinary function be used as a Prober.
+type ProberFunc func(ctx context.Context, address string, port int, opts Options) (PortState, error)
+
+// Probe calls f.
+func (f ProberFunc) Probe(ctx context.Context, address string, port int, opts Options) (PortState, error) {
+	return f(ctx, address, port, opts)
+}
+
+// Dialer opens the connections of the probes. *net.Dialer implements it,
+// tests can set an in-memory one on Options to avoid depending on real sockets.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+func (o Options) dialer() Dialer {
+	if o.Dialer == nil {
+		return &net.Dialer{}
+	}
+
+	return o.Dialer
+}
+
+// dial connects to address through the dialer of opts, giving up after the probe timeout.
+func dial(ctx context.Context, opts Options, network, address string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	return opts.dialer().DialContext(ctx, network, address)
+}
+
+// Names of the built-in scan methods.
+const (
+	MethodConnect = "connect"
+	MethodUDP     = "udp"
+	MethodBanner  = "banner"
+)
+
+var (
+	probersMu sync.RWMutex
+	probers   = map[string]Prober{
+		// MethodConnect grabs banners as well when Options.Banner is set.
+		MethodConnect: ProberFunc(scanTCPPort),
+		MethodUDP:     ProberFunc(scanUDPPort),
+		MethodBanner: ProberFunc(func(ctx context.Context, address string, port int, opts Options) (PortState, error) {
+			opts.Banner = true
+			return scanTCPPort(ctx, address, port, opts)
+		}),
+	}
+)
+
+// RegisterProber makes a prober available under the name of its scan method.
+// The built-in methods cannot be replaced.
+func RegisterProber(method string, p Prober) error {
+	probersMu.Lock()
+	defer probersMu.Unlock()
+
+	if _, ok := probers[method]; ok {
+		return fmt.Errorf("%w:%s", ErrMethodExists, method)
+	}
+
+	probers[method] = p
+	return nil
+}
+
+// LookupProber returns the prober registered for a scan method.
+func LookupProber(method string) (Prober, error) {
+	probersMu.RLock()
+	defer probersMu.RUnlock()
+
+	p, ok := probers[method]
+	if !ok {
+		return nil, fmt.Errorf("%w:%s, use one of %v", ErrUnknownMethod, method, methods())
+	}
+
+	return p, nil
+}
+
+// Methods returns the names of the registered scan methods in alphabetical order.
+func Methods() []string {
+	probersMu.RLock()
+	defer probersMu.RUnlock()
+
+	return methods()
+}
+
+func methods() []string {
+	names := make([]string, 0, len(probers))
+	for name := range probers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// prober returns the prober of opts, falling back to the UDP or the TCP connect
+// method depending on Options.UDP.
+func (o Options) prober() Prober {
+	switch {
+	case o.Prober != nil:
+		return o.Prober
+	case o.UDP:
+		return ProberFunc(scanUDPPort)
+	default:
+		return ProberFunc(scanTCPPort)
+	}
+}
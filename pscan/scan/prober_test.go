@@ -0,0 +1,143 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// memoryNetwork is an in-memory Dialer, its handlers serve the connections to their address.
+// Addresses without a handler refuse the connections, and the blackholed ones never answer.
+type memoryNetwork struct {
+	handlers   map[string]func(conn net.Conn)
+	blackholed map[string]bool
+}
+
+func (n memoryNetwork) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if n.blackholed[address] {
+		<-ctx.Done()
+		return nil, &net.OpError{Op: "dial", Net: network, Err: ctx.Err()}
+	}
+
+	handler, ok := n.handlers[address]
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+	}
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		handler(server)
+	}()
+
+	return client, nil
+}
+
+func TestRunMemoryNetwork(t *testing.T) {
+	network := memoryNetwork{
+		handlers: map[string]func(conn net.Conn){
+			"192.0.2.10:22": func(conn net.Conn) {
+				conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			},
+		},
+		blackholed: map[string]bool{
+			"192.0.2.10:443": true,
+		},
+	}
+
+	hl := scan.HostsList{}
+	hl.Add("192.0.2.10")
+
+	opts := scan.Options{
+		Dialer:      network,
+		Resolver:    fakeResolver{addrs: map[string][]string{"192.0.2.10": {"192.0.2.10"}}},
+		Prober:      mustLookupProber(t, scan.MethodBanner),
+		Timeout:     50 * time.Millisecond,
+		NoDiscovery: true,
+	}
+
+	res := scan.Run(&hl, []int{22, 80, 443}, opts)
+
+	if len(res) != 1 || len(res[0].PortStates) != 3 {
+		t.Fatalf("expected 1 result with 3 port states, got %v instead\n", res)
+	}
+
+	expected := []struct {
+		state   scan.State
+		reason  string
+		service string
+	}{
+		{scan.StateOpen, "syn-ack", "ssh"},
+		{scan.StateClosed, "conn-refused", ""},
+		{scan.StateFiltered, "no-response", ""},
+	}
+
+	for i, ps := range res[0].PortStates {
+		if ps.State != expected[i].state || ps.Reason != expected[i].reason || ps.Service != expected[i].service {
+			t.Errorf("expected port %d to be %s (%s) %s, got %s (%s) %s instead\n",
+				ps.Port, expected[i].state, expected[i].reason, expected[i].service, ps.State, ps.Reason, ps.Service)
+		}
+	}
+}
+
+func TestRegisterProber(t *testing.T) {
+	probed := []int{}
+
+	custom := scan.ProberFunc(func(ctx context.Context, address string, port int, opts scan.Options) (scan.PortState, error) {
+		probed = append(probed, port)
+		return scan.PortState{Port: port, Protocol: "sctp", State: scan.StateOpen, Reason: "init-ack"}, nil
+	})
+
+	if err := scan.RegisterProber("test-sctp", custom); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scan.RegisterProber(scan.MethodConnect, custom); !errors.Is(err, scan.ErrMethodExists) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrMethodExists, err)
+	}
+
+	if !slices.Contains(scan.Methods(), "test-sctp") {
+		t.Errorf("expected methods %v to contain %q\n", scan.Methods(), "test-sctp")
+	}
+
+	if _, err := scan.LookupProber("missing"); !errors.Is(err, scan.ErrUnknownMethod) {
+		t.Errorf("expected error %q, got %q instead\n", scan.ErrUnknownMethod, err)
+	}
+
+	hl := scan.HostsList{}
+	hl.Add("192.0.2.10")
+
+	opts := scan.Options{
+		Workers:     1,
+		Resolver:    fakeResolver{addrs: map[string][]string{"192.0.2.10": {"192.0.2.10"}}},
+		Prober:      mustLookupProber(t, "test-sctp"),
+		NoDiscovery: true,
+	}
+
+	res := scan.Run(&hl, []int{36412, 38412}, opts)
+
+	if !slices.Equal(probed, []int{36412, 38412}) {
+		t.Errorf("expected the custom prober to probe %v, got %v instead\n", []int{36412, 38412}, probed)
+	}
+
+	if len(res) != 1 || len(res[0].PortStates) != 2 || res[0].PortStates[0].Protocol != "sctp" {
+		t.Errorf("expected the custom port states, got %v instead\n", res)
+	}
+}
+
+func mustLookupProber(t *testing.T, method string) scan.Prober {
+	t.Helper()
+
+	p, err := scan.LookupProber(method)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
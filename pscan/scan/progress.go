@@ -0,0 +1,88 @@
+package scan
+
+// Progress is a step of a scan reported to Options.Progress: the discovery of a host, or the probe of one of its ports.
+type Progress struct {
+	// Index is the position of the host in the results of a complete scan.
+	Index   int
+	Host    string
+	Address string
+	// Port is the state of the probed port, it is nil for the discovery of the host.
+	Port *PortState
+	// Results is only set by the step which completes the host, that is once all of its ports are scanned,
+	// or once it turns out to be not found or down. It holds the results of the host.
+	Results *Results
+	// Done and Total count the steps of the scan, a discovery per host and a probe per port.
+	// The ports of the hosts which are not found or down are dropped from Total once they are discovered,
+	// and the ports resumed from an earlier run count as done.
+	Done  int
+	Total int
+}
+
+// progressTracker counts the steps of a scan and reports them to Options.Progress.
+// A nil progressTracker does not report anything. Its methods are called with the lock of the scan held.
+type progressTracker struct {
+	report    func(Progress)
+	done      int
+	total     int
+	remaining []int
+}
+
+func newProgressTracker(report func(Progress), targets []scanTarget) *progressTracker {
+	if report == nil {
+		return nil
+	}
+
+	p := &progressTracker{report: report, total: len(targets), remaining: make([]int, len(targets))}
+	for i, t := range targets {
+		p.remaining[i] = len(t.ports)
+		p.total += len(t.ports)
+	}
+
+	return p
+}
+
+// discovered reports the discovery of the host of target i, the ports resumed from an earlier run included.
+func (p *progressTracker) discovered(i int, t *scanTarget, scanned []bool) {
+	if p == nil {
+		return
+	}
+
+	p.done++
+
+	if t.res.PortStates == nil {
+		p.total -= p.remaining[i]
+		p.remaining[i] = 0
+	}
+
+	for _, s := range scanned {
+		if s {
+			p.done++
+			p.remaining[i]--
+		}
+	}
+
+	p.step(i, t, nil, scanned)
+}
+
+// probed reports the probe of a port of target i.
+func (p *progressTracker) probed(i int, t *scanTarget, ps PortState, scanned []bool) {
+	if p == nil {
+		return
+	}
+
+	p.done++
+	p.remaining[i]--
+
+	p.step(i, t, &ps, scanned)
+}
+
+func (p *progressTracker) step(i int, t *scanTarget, ps *PortState, scanned []bool) {
+	progress := Progress{Index: i, Host: t.res.Host, Address: t.res.Address, Port: ps, Done: p.done, Total: p.total}
+
+	if p.remaining[i] == 0 {
+		res := scannedResults(t.res, scanned)
+		progress.Results = &res
+	}
+
+	p.report(progress)
+}
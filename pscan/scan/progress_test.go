@@ -0,0 +1,79 @@
+package scan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestRunProgress(t *testing.T) {
+	resolver := fakeResolver{addrs: map[string][]string{"host1": {"10.0.0.1"}}}
+
+	hl := &scan.HostsList{}
+	for _, host := range []string{"host1", "host2"} {
+		if err := hl.Add(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []scan.Progress{}
+	opts := scan.Options{
+		Resolver:    resolver,
+		Prober:      &recordingProber{},
+		NoDiscovery: true,
+		Workers:     1,
+		// Port 22 of host1 is resumed, it counts as done without a probe.
+		Resume: []scan.Results{{Host: "host1", Address: "10.0.0.1", PortStates: []scan.PortState{
+			{Port: 22, Protocol: scan.ProtocolTCP, State: scan.StateClosed},
+		}}},
+		Progress: func(p scan.Progress) {
+			steps = append(steps, p)
+		},
+	}
+
+	res, err := scan.RunContext(context.Background(), hl, []int{22, 80, 443}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	// A discovery per host and a probe per remaining port of host1.
+	if len(steps) != 4 {
+		t.Fatalf("expected 4 steps, got %d instead\n", len(steps))
+	}
+
+	last := steps[len(steps)-1]
+	// host2 is not found, its ports are not part of the total.
+	if last.Done != 5 || last.Total != 5 {
+		t.Errorf("expected 5/5 steps done, got %d/%d instead\n", last.Done, last.Total)
+	}
+
+	completed := map[string]scan.Results{}
+	for _, p := range steps {
+		if p.Done > p.Total {
+			t.Errorf("expected no more than %d steps done, got %d instead\n", p.Total, p.Done)
+		}
+
+		if p.Port != nil && p.Port.Port == 22 {
+			t.Errorf("expected no probe of the resumed port 22\n")
+		}
+
+		if p.Results == nil {
+			continue
+		}
+
+		if _, ok := completed[p.Host]; ok {
+			t.Errorf("expected %s to complete once\n", p.Host)
+		}
+
+		if p.Results.Host != res[p.Index].Host || len(p.Results.PortStates) != len(res[p.Index].PortStates) {
+			t.Errorf("expected the results %v of host %d, got %v instead\n", res[p.Index], p.Index, *p.Results)
+		}
+
+		completed[p.Host] = *p.Results
+	}
+
+	if len(completed) != 2 || !completed["host2"].NotFound || len(completed["host1"].PortStates) != 3 {
+		t.Errorf("expected host1 with 3 ports and host2 not found, got %v instead\n", completed)
+	}
+}
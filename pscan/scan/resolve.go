@@ -0,0 +1,148 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Resolver looks up the addresses of host names and the names of addresses.
+// *net.Resolver implements it, tests can set a fake one on Options to avoid depending on DNS.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+func (o Options) resolver() Resolver {
+	if o.Resolver == nil {
+		return net.DefaultResolver
+	}
+
+	return o.Resolver
+}
+
+// AddressMode selects which of the resolved addresses of a host are scanned.
+type AddressMode int
+
+const (
+	// AddressIPv4 scans the first IPv4 address of the host, or its first address if it has no IPv4 one.
+	AddressIPv4 AddressMode = iota
+	// AddressIPv6 scans the first IPv6 address of the host, or its first address if it has no IPv6 one.
+	AddressIPv6
+	// AddressAll scans every address of the host separately, each with its own results.
+	AddressAll
+)
+
+var addressModeNames = map[AddressMode]string{
+	AddressIPv4: "ipv4",
+	AddressIPv6: "ipv6",
+	AddressAll:  "all",
+}
+
+// String converts the address mode to its name.
+func (m AddressMode) String() string {
+	if name, ok := addressModeNames[m]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("AddressMode(%d)", int(m))
+}
+
+// ParseAddressMode converts the name of an address mode, ipv4, ipv6 or all, into an AddressMode.
+func ParseAddressMode(name string) (AddressMode, error) {
+	for mode, n := range addressModeNames {
+		if n == name {
+			return mode, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w:%q, use one of ipv4, ipv6 or all", ErrInvalidAddressMode, name)
+}
+
+// pick returns the addresses to scan out of the resolved ones.
+func (m AddressMode) pick(addrs []string) []string {
+	if m == AddressAll || len(addrs) == 0 {
+		return addrs
+	}
+
+	for _, a := range addrs {
+		ip, err := netip.ParseAddr(a)
+		if err != nil {
+			continue
+		}
+
+		if ip.Unmap().Is4() == (m == AddressIPv4) {
+			return []string{a}
+		}
+	}
+
+	return addrs[:1]
+}
+
+// scanTarget is a single address of a host to scan, the results are filled as the scan goes.
+type scanTarget struct {
+	res   Results
+	ports []int
+}
+
+// resolveTargets looks up the addresses of the targets and returns a scan target
+// per address to scan, in the order of the targets. Targets which cannot be found
+// are kept with NotFound set, while those whose lookup is cut short by ctx are left out.
+func resolveTargets(ctx context.Context, targets []target, opts Options) []scanTarget {
+	resolved := make([][]scanTarget, len(targets))
+
+	parallel(ctx, len(targets), opts.workers(), func(i int) {
+		t := targets[i]
+		r := Results{Host: t.host}
+
+		addrs, err := opts.resolver().LookupHost(ctx, t.host)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			r.NotFound = true
+			resolved[i] = []scanTarget{{res: r}}
+			return
+		}
+
+		r.Addresses = addrs
+
+		// The names of a host given by its address are only known through reverse DNS.
+		// A missing reverse record is common and nothing to report.
+		if net.ParseIP(t.host) != nil {
+			if names, err := opts.resolver().LookupAddr(ctx, t.host); err == nil {
+				r.Names = trimDots(names)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		for _, addr := range opts.AddressMode.pick(addrs) {
+			st := scanTarget{res: r, ports: t.ports}
+			st.res.Address = addr
+			resolved[i] = append(resolved[i], st)
+		}
+	})
+
+	scanTargets := make([]scanTarget, 0, len(targets))
+	for _, r := range resolved {
+		scanTargets = append(scanTargets, r...)
+	}
+
+	return scanTargets
+}
+
+// trimDots removes the trailing dots of fully qualified names returned by DNS.
+func trimDots(names []string) []string {
+	trimmed := make([]string, 0, len(names))
+	for _, n := range names {
+		trimmed = append(trimmed, strings.TrimSuffix(n, "."))
+	}
+
+	return trimmed
+}
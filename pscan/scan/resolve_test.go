@@ -0,0 +1,155 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+// fakeResolver answers lookups from its maps instead of DNS.
+type fakeResolver struct {
+	addrs map[string][]string
+	names map[string][]string
+}
+
+func (r fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.addrs[host]; ok {
+		return addrs, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if names, ok := r.names[addr]; ok {
+		return names, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+func TestParseAddressMode(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expected    scan.AddressMode
+		expectedErr error
+	}{
+		{"ipv4", scan.AddressIPv4, nil},
+		{"ipv6", scan.AddressIPv6, nil},
+		{"all", scan.AddressAll, nil},
+		{"first", 0, scan.ErrInvalidAddressMode},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mode, err := scan.ParseAddressMode(tc.name)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v instead\n", tc.expectedErr, err)
+			}
+
+			if mode != tc.expected {
+				t.Errorf("expected mode %s, got %s instead\n", tc.expected, mode)
+			}
+		})
+	}
+}
+
+func TestRunAddressMode(t *testing.T) {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	resolver := fakeResolver{addrs: map[string][]string{
+		"dual": {"::1", "127.0.0.1"},
+	}}
+
+	testCases := []struct {
+		name     string
+		mode     scan.AddressMode
+		expected []string
+	}{
+		{"IPv4", scan.AddressIPv4, []string{"127.0.0.1"}},
+		{"IPv6", scan.AddressIPv6, []string{"::1"}},
+		{"All", scan.AddressAll, []string{"::1", "127.0.0.1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := scan.HostsList{}
+			hl.Add("dual")
+
+			opts := scan.Options{Resolver: resolver, AddressMode: tc.mode, NoDiscovery: true}
+			res := scan.Run(&hl, []int{port}, opts)
+
+			addresses := []string{}
+			for _, r := range res {
+				if r.Host != "dual" {
+					t.Errorf("expected host %q, got %q instead\n", "dual", r.Host)
+				}
+
+				if !slices.Equal(r.Addresses, resolver.addrs["dual"]) {
+					t.Errorf("expected addresses %v, got %v instead\n", resolver.addrs["dual"], r.Addresses)
+				}
+
+				addresses = append(addresses, r.Address)
+
+				// Only the IPv4 address listens, whatever happens on the IPv6 one.
+				if r.Address == "127.0.0.1" && r.PortStates[0].State != scan.StateOpen {
+					t.Errorf("expected port %d to be open on %s, got %s instead\n", port, r.Address, r.PortStates[0].State)
+				}
+			}
+
+			if !slices.Equal(addresses, tc.expected) {
+				t.Errorf("expected scanned addresses %v, got %v instead\n", tc.expected, addresses)
+			}
+		})
+	}
+}
+
+func TestRunReverseDNS(t *testing.T) {
+	resolver := fakeResolver{
+		addrs: map[string][]string{
+			"127.0.0.1": {"127.0.0.1"},
+			"localhost": {"127.0.0.1"},
+		},
+		names: map[string][]string{
+			"127.0.0.1": {"web.example.com."},
+		},
+	}
+
+	hl := scan.HostsList{}
+	hl.Add("127.0.0.1")
+	hl.Add("localhost")
+	hl.Add("missing")
+
+	res := scan.Run(&hl, nil, scan.Options{Resolver: resolver, NoDiscovery: true})
+
+	expected := map[string][]string{
+		"127.0.0.1": {"web.example.com"},
+		"localhost": nil,
+		"missing":   nil,
+	}
+
+	if len(res) != len(expected) {
+		t.Fatalf("expected %d results, got %d instead\n", len(expected), len(res))
+	}
+
+	for _, r := range res {
+		if !slices.Equal(r.Names, expected[r.Host]) {
+			t.Errorf("expected names %v for %s, got %v instead\n", expected[r.Host], r.Host, r.Names)
+		}
+
+		if r.Host == "missing" && !r.NotFound {
+			t.Errorf("expected host %q not to be found\n", r.Host)
+		}
+	}
+}
@@ -1,75 +1,501 @@
 package scan
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net"
+	"runtime"
+	"sync"
+	"syscall"
 	"time"
 )
 
-// PortState represents the state of a single TCP port.
+// DefaultWorkers is the number of concurrent workers used by Run
+// when Options does not define one.
+var DefaultWorkers = runtime.NumCPU() * 8
+
+// DefaultTimeout is how long a single probe waits for the target to answer
+// when Options does not define it.
+const DefaultTimeout = 1 * time.Second
+
+// Supported transport protocols of a port scan.
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// PortState represents the state of a single TCP or UDP port.
 type PortState struct {
-	Port int
-	Open state
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	State    State  `json:"state"`
+	// Reason explains why the port got its state, e.g. "conn-refused" for a closed TCP port.
+	// The names follow the reasons reported by Nmap.
+	Reason string `json:"reason,omitempty"`
+	// Latency is the time it took to get the answer, or to give up on it.
+	Latency time.Duration `json:"latency"`
+	// Banner, Service and Version are only set for open ports when banner grabbing is enabled.
+	Banner  string `json:"banner,omitempty"`
+	Service string `json:"service,omitempty"`
+	Version string `json:"version,omitempty"`
+	// TLS is only set for open TCP ports which speak TLS when TLS inspection is enabled.
+	TLS *TLSInfo `json:"tls,omitempty"`
+	// HTTP is only set for open TCP ports which speak HTTP when HTTP probing is enabled.
+	HTTP *HTTPInfo `json:"http,omitempty"`
 }
 
+// Results represents the outcome of scanning a single host.
 type Results struct {
-	Host       string
-	NotFound   bool
-	PortStates []PortState
+	Host     string `json:"host"`
+	NotFound bool   `json:"notFound"`
+	// Address is the address which was scanned, out of all the Addresses the host resolves to.
+	Address   string   `json:"address,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	// Names are the reverse DNS names of a host given by its address.
+	Names []string `json:"names,omitempty"`
+	// Status and Reason tell whether the host answered the discovery probes and how.
+	// The ports of a host which is down are not scanned.
+	Status     HostStatus  `json:"status,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	PortStates []PortState `json:"ports,omitempty"`
+}
+
+// Options tunes how Run performs the scan.
+// The zero value is ready to use.
+type Options struct {
+	// Workers is the upper limit of concurrent lookups and port scans.
+	Workers int
+
+	// UDP scans the UDP ports instead of the TCP ones.
+	UDP bool
+
+	// Banner reads the greeting of the open TCP ports to guess the service behind them.
+	Banner bool
+
+	// Timeout is how long a single probe waits for the target to answer.
+	Timeout time.Duration
+
+	// Retries is how many more times a probe is sent when it gets no conclusive answer,
+	// that is when the port looks filtered or the probe fails.
+	Retries int
+
+	// Rate is the upper limit of probes sent per second across all hosts, 0 means unlimited.
+	Rate int
+
+	// MaxPerHost is the upper limit of concurrent probes to a single host, 0 means unlimited.
+	MaxPerHost int
+
+	// Randomize scans the hosts and ports in a random order instead of the list order.
+	// The results keep the list order either way.
+	Randomize bool
+
+	// Jitter is the upper limit of a random delay added before every probe.
+	Jitter time.Duration
+
+	// NoDiscovery skips checking whether the hosts are up, so that every host gets its ports scanned.
+	NoDiscovery bool
+
+	// DiscoveryPorts are the TCP ports probed to check whether a host is up,
+	// DefaultDiscoveryPorts are used when it is empty.
+	DiscoveryPorts []int
+
+	// AddressMode selects which of the addresses of a host are scanned, the first IPv4 one by default.
+	AddressMode AddressMode
+
+	// Resolver looks up the hosts, net.DefaultResolver is used when it is nil.
+	Resolver Resolver
+
+	// Prober probes the ports, when it is nil the method is picked by UDP.
+	// See LookupProber for the registered methods.
+	Prober Prober
+
+	// Dialer opens the connections of the probes, a net.Dialer is used when it is nil.
+	Dialer Dialer
+
+	// TLS performs a TLS handshake on the open TCP ports to record their certificates.
+	TLS bool
+
+	// TLSRoots are the certificate authorities trusted when verifying the certificates,
+	// the system ones are used when it is nil.
+	TLSRoots *x509.CertPool
+
+	// HTTP requests the root page of the open TCP ports to record the answer of their web servers.
+	HTTP bool
+
+	// Checkpoint is the file the progress of the scan is saved to every CheckpointInterval,
+	// and once more when the scan is interrupted, so that it can be resumed. See LoadCheckpoint.
+	Checkpoint string
+
+	// CheckpointInterval is how often the checkpoint is saved, DefaultCheckpointInterval by default.
+	CheckpointInterval time.Duration
+
+	// Resume holds the results of an interrupted run of the same scan, usually those of its checkpoint.
+	// The hosts and ports they hold are not scanned again, their results are merged into the new ones.
+	Resume []Results
+
+	// Progress is called after every step of the scan, so that the results can be shown as they come.
+	// The calls are never concurrent, but they hold up the scan, so they should return quickly.
+	Progress func(Progress)
+}
+
+func (o Options) workers() int {
+	if o.Workers < 1 {
+		return DefaultWorkers
+	}
+
+	return o.Workers
 }
 
+func (o Options) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return o.Timeout
+}
+
+// ServiceName returns the detected service of the port,
+// or the well known service of the port number when nothing was detected.
+func (p PortState) ServiceName() string {
+	if p.Service != "" {
+		return p.Service
+	}
+
+	return ServiceName(p.Port)
+}
+
+// State is the state of a scanned port.
 // By creating custom types, we can associate methods to it.
-type state bool
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	// StateOpenFiltered is used when a probe gets no answer, which happens both
+	// when a UDP service ignores the probe and when a firewall drops it.
+	StateOpenFiltered
+	// StateFiltered is used when the probe is dropped or rejected on the way,
+	// so there is no telling whether a service listens on the port.
+	StateFiltered
+	// StateError is used when the probe fails for a reason unrelated to the target port.
+	StateError
+)
+
+var stateNames = map[State]string{
+	StateClosed:       "closed",
+	StateOpen:         "open",
+	StateOpenFiltered: "open|filtered",
+	StateFiltered:     "filtered",
+	StateError:        "error",
+}
 
-// String converts the boolean value of state to a human readable string
-func (s state) String() string {
-	if s {
-		return "open"
+// String converts the state to a human readable string.
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
 	}
 
-	return "closed"
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// scanPort performs a port scan on a single port by using the prober selected in opts.
+// The returned error is only set when ctx ends before the scan completes,
+// in which case the port state is unknown.
+func scanPort(ctx context.Context, host string, port int, opts Options) (PortState, error) {
+	return opts.prober().Probe(ctx, host, port, opts)
 }
 
-// scanPort performs a port scan on a single TCP port
-func scanPort(host string, port int) PortState {
+// scanTCPPort performs a connect scan on a single TCP port.
+func scanTCPPort(ctx context.Context, host string, port int, opts Options) (PortState, error) {
 	p := PortState{
-		Port: port,
+		Port:     port,
+		Protocol: ProtocolTCP,
 	}
 
 	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	scanConn, err := net.DialTimeout("tcp", address, 1*time.Second)
+	start := time.Now()
+	scanConn, err := dial(ctx, opts, "tcp", address)
+	p.Latency = time.Since(start)
+
 	if err != nil {
-		// Assume that error means the port is not open.
-		return p
+		if ctx.Err() != nil {
+			return p, ctx.Err()
+		}
+
+		p.State, p.Reason = errorState(err)
+		return p, nil
 	}
 
-	scanConn.Close()
-	p.Open = true
-	return p
+	defer scanConn.Close()
+	p.State, p.Reason = StateOpen, "syn-ack"
+
+	if opts.Banner {
+		grabBanner(ctx, scanConn, &p, opts.timeout())
+	}
+
+	return p, nil
+}
+
+// errorState classifies the error of a probe into a port state and its reason.
+// A refused connection means that the host answered but nothing listens on the port,
+// while timeouts and unreachable errors mean that something dropped the probe on the way.
+func errorState(err error) (State, string) {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StateClosed, "conn-refused"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return StateFiltered, "no-response"
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return StateFiltered, "host-unreach"
+	case errors.Is(err, syscall.ENETUNREACH):
+		return StateFiltered, "net-unreach"
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return StateFiltered, "admin-prohibited"
+	default:
+		return StateError, err.Error()
+	}
 }
 
 // Run performs a port scan on the hosts list.
-func Run(hl *HostsList, ports []int) []Results {
-	res := make([]Results, 0, len(hl.Hosts))
+// Hosts and ports are scanned concurrently by a bounded pool of workers,
+// but the results keep the order of the hosts list and the given ports.
+// Hosts with a port override in the hosts list are scanned on their own ports instead.
+// Unless opts.NoDiscovery is set, the hosts which are down are not scanned, see Discover.
+func Run(hl *HostsList, ports []int, opts Options) []Results {
+	// Background context is never cancelled, so there is no error to check.
+	res, _ := RunContext(context.Background(), hl, ports, opts)
+	return res
+}
+
+// RunContext performs a port scan on the hosts list like Run, but stops
+// as soon as ctx is cancelled or its deadline passes.
+// In that case the results gathered so far are returned together with the context error.
+// When opts.Checkpoint is set, the final checkpoint is saved then, and a failure to save it is returned as well.
+func RunContext(ctx context.Context, hl *HostsList, ports []int, opts Options) ([]Results, error) {
+	// The patterns of the hosts list are only expanded now, the file keeps them compact.
+	// Hosts are scanned on their addresses, so that every probe goes to the same place.
+	targets := resolveTargets(ctx, hl.targets(ports), opts)
+
+	// Keep track of the finished work, so that an interrupted scan does not
+	// report hosts and ports which were never checked.
+	// The workers hold mu while they record their work, so that checkpoints see it whole.
+	mu := sync.Mutex{}
+	ready := make([]bool, len(targets))
+	scanned := make([][]bool, len(targets))
+
+	snapshot := func() []Results {
+		mu.Lock()
+		defer mu.Unlock()
 
-	for _, h := range hl.Hosts {
-		r := Results{
-			Host: h,
+		res := make([]Results, len(targets))
+		for i, t := range targets {
+			res[i] = t.res
 		}
 
-		// Resolve the host name into a volid IP address.
-		if _, err := net.LookupHost(h); err != nil {
-			r.NotFound = true
-			res = append(res, r)
-			continue
+		return partialResults(res, ready, scanned)
+	}
+
+	stopCheckpoints := startCheckpoints(opts, snapshot)
+	progress := newProgressTracker(opts.Progress, targets)
+	resumed := resumeIndex(opts.Resume)
+	limiter := newRateLimiter(opts.Rate)
+
+	// Check that the hosts are up first, there is no point in scanning the ports
+	// of a host which cannot be found or does not answer.
+	parallel(ctx, len(targets), opts.workers(), func(i int) {
+		t := &targets[i]
+		previous, isResumed := resumed[resumeKey{t.res.Host, t.res.Address}]
+
+		status, reason := t.res.Status, t.res.Reason
+		switch {
+		case isResumed:
+			status, reason = previous.Status, previous.Reason
+		case !t.res.NotFound && !opts.NoDiscovery:
+			var err error
+			if status, reason, err = discoverHost(ctx, t.res.Address, opts, limiter); err != nil {
+				return
+			}
 		}
 
-		for _, p := range ports {
-			r.PortStates = append(r.PortStates, scanPort(h, p))
+		mu.Lock()
+		defer mu.Unlock()
+
+		t.res.Status, t.res.Reason = status, reason
+
+		if !t.res.NotFound && t.res.Status != HostDown {
+			t.res.PortStates = make([]PortState, len(t.ports))
+			scanned[i] = make([]bool, len(t.ports))
+
+			resumePorts(t, previous.PortStates, scanned[i])
 		}
 
-		res = append(res, r)
+		ready[i] = true
+		progress.discovered(i, t, scanned[i])
+	})
+
+	// Flatten the target/port pairs into jobs to spread them evenly across the workers.
+	// The jobs go round robin over the targets, so that no host gets all the probes at once.
+	// Hosts which are not found or down have no port states to fill.
+	type job struct {
+		target int
+		port   int
 	}
 
-	return res
+	maxPorts := 0
+	for _, t := range targets {
+		maxPorts = max(maxPorts, len(t.ports))
+	}
+
+	jobs := []job{}
+	for p := 0; p < maxPorts; p++ {
+		for i, t := range targets {
+			if !ready[i] || t.res.PortStates == nil || p >= len(t.ports) || scanned[i][p] {
+				continue
+			}
+
+			jobs = append(jobs, job{target: i, port: p})
+		}
+	}
+
+	if opts.Randomize {
+		rand.Shuffle(len(jobs), func(i, j int) {
+			jobs[i], jobs[j] = jobs[j], jobs[i]
+		})
+	}
+
+	slots := newHostSlots(len(targets), opts.MaxPerHost)
+
+	parallel(ctx, len(jobs), opts.workers(), func(i int) {
+		j := jobs[i]
+		t := &targets[j.target]
+
+		if err := slots.acquire(ctx, j.target); err != nil {
+			return
+		}
+		defer slots.release(j.target)
+
+		ps, err := probePort(ctx, t.res.Address, t.ports[j.port], opts, limiter)
+		if err != nil {
+			return
+		}
+
+		if opts.TLS && ps.State == StateOpen && ps.Protocol == ProtocolTCP {
+			ps.TLS = inspectTLS(ctx, t.res.Host, t.res.Address, ps.Port, opts)
+		}
+
+		if opts.HTTP && ps.State == StateOpen && ps.Protocol == ProtocolTCP {
+			ps.HTTP = probeHTTP(ctx, t.res.Host, t.res.Address, ps.Port, opts)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Each job writes to its own index, so the order is kept.
+		t.res.PortStates[j.port] = ps
+		scanned[j.target][j.port] = true
+		progress.probed(j.target, t, ps, scanned[j.target])
+	})
+
+	stopCheckpoints()
+
+	// Without interruption every host and port is scanned, and the partial results are the whole ones.
+	res, err := snapshot(), ctx.Err()
+
+	if opts.Checkpoint != "" && err != nil {
+		if saveErr := SaveCheckpoint(opts.Checkpoint, Checkpoint{Time: time.Now(), Results: res}); saveErr != nil {
+			err = errors.Join(err, fmt.Errorf("cannot save the checkpoint: %w", saveErr))
+		}
+	}
+
+	return res, err
+}
+
+// resumePorts copies the states of the ports which were scanned by an earlier run into the target.
+func resumePorts(t *scanTarget, previous []PortState, scanned []bool) {
+	states := map[int]PortState{}
+	for _, ps := range previous {
+		states[ps.Port] = ps
+	}
+
+	for p, port := range t.ports {
+		if ps, ok := states[port]; ok {
+			t.res.PortStates[p] = ps
+			scanned[p] = true
+		}
+	}
+}
+
+// partialResults drops the hosts and ports which were not scanned
+// before the scan got interrupted.
+func partialResults(res []Results, ready []bool, scanned [][]bool) []Results {
+	partial := make([]Results, 0, len(res))
+
+	for h, r := range res {
+		if ready[h] {
+			partial = append(partial, scannedResults(r, scanned[h]))
+		}
+	}
+
+	return partial
+}
+
+// scannedResults copies the results of a host with the ports scanned so far.
+func scannedResults(r Results, scanned []bool) Results {
+	if r.NotFound {
+		return r
+	}
+
+	states := make([]PortState, 0, len(r.PortStates))
+	for p, ps := range r.PortStates {
+		if scanned[p] {
+			states = append(states, ps)
+		}
+	}
+
+	r.PortStates = states
+	return r
+}
+
+// parallel calls fn for every index in [0, n) by using at most workers goroutines.
+// It stops handing out indexes once ctx is done and returns when all started calls are done.
+func parallel(ctx context.Context, n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+
+	indexCh := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexCh {
+				// The select below may still hand out an index after cancellation.
+				if ctx.Err() != nil {
+					continue
+				}
+
+				fn(i)
+			}
+		}()
+	}
+
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case indexCh <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(indexCh)
+
+	wg.Wait()
 }
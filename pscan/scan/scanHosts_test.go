@@ -1,9 +1,13 @@
 package scan_test
 
 import (
+	"context"
+	"errors"
 	"net"
+	"slices"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/acikgozb/cli-playground/pscan/scan"
 )
@@ -11,24 +15,31 @@ import (
 func TestStateString(t *testing.T) {
 	ps := scan.PortState{}
 
-	if ps.Open.String() != "closed" {
-		t.Errorf("expected %q as port state but got %q", "closed", ps.Open.String())
+	if ps.State.String() != "closed" {
+		t.Errorf("expected %q as port state but got %q", "closed", ps.State.String())
 	}
 
-	ps.Open = true
+	ps.State = scan.StateOpen
 
-	if ps.Open.String() != "open" {
-		t.Errorf("expected %q as port state but got %q", "open", ps.Open.String())
+	if ps.State.String() != "open" {
+		t.Errorf("expected %q as port state but got %q", "open", ps.State.String())
+	}
+
+	ps.State = scan.StateOpenFiltered
+
+	if ps.State.String() != "open|filtered" {
+		t.Errorf("expected %q as port state but got %q", "open|filtered", ps.State.String())
 	}
 }
 
 func TestRunHostFound(t *testing.T) {
 	testCases := []struct {
-		name          string
-		expectedState string
+		name           string
+		expectedState  string
+		expectedReason string
 	}{
-		{"OpenPort", "open"},
-		{"ClosedPort", "closed"},
+		{"OpenPort", "open", "syn-ack"},
+		{"ClosedPort", "closed", "conn-refused"},
 	}
 
 	host := "localhost"
@@ -65,7 +76,7 @@ func TestRunHostFound(t *testing.T) {
 	}
 
 	// When
-	res := scan.Run(&hl, ports)
+	res := scan.Run(&hl, ports, scan.Options{})
 
 	// Then
 	if len(res) != 1 {
@@ -89,20 +100,30 @@ func TestRunHostFound(t *testing.T) {
 			t.Errorf("expected port %d, got %d instead\n", ports[i], res[0].PortStates[i].Port)
 		}
 
-		if res[0].PortStates[i].Open.String() != tc.expectedState {
+		if res[0].PortStates[i].State.String() != tc.expectedState {
 			t.Errorf("expected port %d to be %s\n", ports[i], tc.expectedState)
 		}
+
+		if res[0].PortStates[i].Reason != tc.expectedReason {
+			t.Errorf(
+				"expected reason %q for port %d, got %q instead\n",
+				tc.expectedReason,
+				ports[i],
+				res[0].PortStates[i].Reason,
+			)
+		}
 	}
 }
 
 func TestRunHostNotFound(t *testing.T) {
-	// This host should fail to be found unless you have it on your DNS:
-	host := "389.389.389.389"
+	// This host should fail to be found unless you have it on your DNS,
+	// an invalid address such as 389.389.389.389 is not even accepted by the list.
+	host := "unknownhostoutthere"
 
 	hl := scan.HostsList{}
 	hl.Add(host)
 
-	res := scan.Run(&hl, []int{})
+	res := scan.Run(&hl, []int{}, scan.Options{})
 
 	// Verify the output - one Results, not found, empty PortState
 	if len(res) != 1 {
@@ -121,3 +142,150 @@ func TestRunHostNotFound(t *testing.T) {
 		t.Errorf("expected no portStates, but got %d portStates", len(res[0].PortStates))
 	}
 }
+
+func TestRunKeepsOrder(t *testing.T) {
+	host := "localhost"
+	hl := scan.HostsList{}
+
+	hl.Add(host)
+
+	// Open every other port so that the order of the results can be verified.
+	ports := []int{}
+	expectedStates := []string{}
+	for i := 0; i < 20; i++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer ln.Close()
+
+		port := ln.Addr().(*net.TCPAddr).Port
+		ports = append(ports, port)
+
+		if i%2 == 0 {
+			expectedStates = append(expectedStates, "open")
+			continue
+		}
+
+		ln.Close()
+		expectedStates = append(expectedStates, "closed")
+	}
+
+	res := scan.Run(&hl, ports, scan.Options{Workers: 4})
+
+	if len(res) != 1 {
+		t.Fatalf("expected 1 result, got %d instead\n", len(res))
+	}
+
+	if len(res[0].PortStates) != len(ports) {
+		t.Fatalf("expected %d port states, got %d instead\n", len(ports), len(res[0].PortStates))
+	}
+
+	for i, ps := range res[0].PortStates {
+		if ps.Port != ports[i] {
+			t.Errorf("expected port %d at index %d, got %d instead\n", ports[i], i, ps.Port)
+		}
+
+		if ps.State.String() != expectedStates[i] {
+			t.Errorf("expected port %d to be %s\n", ports[i], expectedStates[i])
+		}
+	}
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// Cancel the scan before it starts, nothing should be reported as scanned.
+	cancel()
+
+	res, err := scan.RunContext(ctx, &hl, []int{ln.Addr().(*net.TCPAddr).Port}, scan.Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected error %q, got %q instead\n", context.Canceled, err)
+	}
+
+	for _, r := range res {
+		if r.NotFound {
+			t.Errorf("expected cancelled lookup of %q not to be reported as not found\n", r.Host)
+		}
+
+		if len(r.PortStates) != 0 {
+			t.Errorf("expected no port states, got %d instead\n", len(r.PortStates))
+		}
+	}
+}
+
+func TestRunContextDone(t *testing.T) {
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := scan.RunContext(ctx, &hl, []int{ln.Addr().(*net.TCPAddr).Port}, scan.Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %q instead\n", err)
+	}
+
+	if len(res) != 1 || len(res[0].PortStates) != 1 {
+		t.Fatalf("expected 1 result with 1 port state, got %v instead\n", res)
+	}
+
+	if res[0].PortStates[0].State.String() != "open" {
+		t.Errorf("expected port to be open\n")
+	}
+}
+
+func TestRunPortOverride(t *testing.T) {
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	hl := scan.HostsList{}
+	hl.Add("localhost")
+	hl.Add("127.0.0.1")
+
+	// Only localhost overrides the ports, 127.0.0.1 is scanned on the given ports.
+	if err := hl.SetInfo("localhost", scan.HostInfo{Ports: strconv.Itoa(port)}); err != nil {
+		t.Fatal(err)
+	}
+
+	res := scan.Run(&hl, []int{1, 2}, scan.Options{})
+
+	expectedPorts := map[string][]int{
+		"localhost": {port},
+		"127.0.0.1": {1, 2},
+	}
+
+	for _, r := range res {
+		ports := []int{}
+		for _, ps := range r.PortStates {
+			ports = append(ports, ps.Port)
+		}
+
+		if !slices.Equal(ports, expectedPorts[r.Host]) {
+			t.Errorf("expected %s to be scanned on ports %v, got %v instead\n", r.Host, expectedPorts[r.Host], ports)
+		}
+	}
+}
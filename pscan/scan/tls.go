@@ -0,0 +1,106 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// TLSInfo describes the TLS session and the certificate served on a port.
+type TLSInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	Subject     string `json:"subject"`
+	// SANs are the DNS names and the IP addresses of the certificate.
+	SANs      []string  `json:"sans,omitempty"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	// VerifyError explains why the certificate is not trusted for the host, it is empty for a trusted one.
+	VerifyError string `json:"verifyError,omitempty"`
+}
+
+// ExpiresWithin reports whether the certificate is expired or expires within d of now.
+func (i TLSInfo) ExpiresWithin(d time.Duration, now time.Time) bool {
+	return i.NotAfter.Before(now.Add(d))
+}
+
+// String summarizes the certificate the way Nmap's ssl-cert script does.
+func (i TLSInfo) String() string {
+	s := fmt.Sprintf("Subject: %s\nIssuer: %s\n", i.Subject, i.Issuer)
+
+	if len(i.SANs) > 0 {
+		s += fmt.Sprintf("Subject Alternative Name: %s\n", strings.Join(i.SANs, ", "))
+	}
+
+	s += fmt.Sprintf("Not valid before: %s\nNot valid after:  %s\n",
+		i.NotBefore.UTC().Format(time.RFC3339), i.NotAfter.UTC().Format(time.RFC3339))
+	s += fmt.Sprintf("Protocol: %s, cipher: %s", i.Version, i.CipherSuite)
+
+	return s
+}
+
+// inspectTLS performs a TLS handshake on an open TCP port and records what the server presents.
+// It returns nil when the port does not speak TLS.
+// Any certificate is accepted for the handshake, the verification against the host is only recorded,
+// since an untrusted certificate is precisely what an inspection should report.
+func inspectTLS(ctx context.Context, host, address string, port int, opts Options) *TLSInfo {
+	conn, err := dial(ctx, opts, "tcp", net.JoinHostPort(address, fmt.Sprintf("%d", port)))
+	if err != nil {
+		return nil
+	}
+
+	defer conn.Close()
+
+	// Servers pick the certificate by the server name, which cannot be an address.
+	serverName := host
+	if net.ParseIP(host) != nil {
+		serverName = ""
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	cert := state.PeerCertificates[0]
+
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+	}
+
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	verifyOpts := x509.VerifyOptions{DNSName: host, Intermediates: intermediates, Roots: opts.TLSRoots}
+	if _, err := cert.Verify(verifyOpts); err != nil {
+		info.VerifyError = err.Error()
+	}
+
+	return info
+}
@@ -0,0 +1,100 @@
+package scan_test
+
+import (
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestRunTLS(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	plainServer := httptest.NewServer(http.NotFoundHandler())
+	defer plainServer.Close()
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(tlsServer.Certificate())
+
+	tlsPort := tlsServer.Listener.Addr().(*net.TCPAddr).Port
+	plainPort := plainServer.Listener.Addr().(*net.TCPAddr).Port
+
+	testCases := []struct {
+		name      string
+		roots     *x509.CertPool
+		untrusted bool
+	}{
+		{"Untrusted", nil, true},
+		{"Trusted", trusted, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := scan.HostsList{}
+			hl.Add("127.0.0.1")
+
+			opts := scan.Options{TLS: true, TLSRoots: tc.roots, NoDiscovery: true}
+			res := scan.Run(&hl, []int{tlsPort, plainPort}, opts)
+
+			if len(res) != 1 || len(res[0].PortStates) != 2 {
+				t.Fatalf("expected 1 result with 2 port states, got %v instead\n", res)
+			}
+
+			info := res[0].PortStates[0].TLS
+			if info == nil {
+				t.Fatalf("expected TLS info for port %d\n", tlsPort)
+			}
+
+			if info.Version == "" || info.CipherSuite == "" {
+				t.Errorf("expected the TLS version and cipher suite, got %+v instead\n", info)
+			}
+
+			// The certificate of httptest is issued to example.com and the loopback addresses.
+			if !slices.Contains(info.SANs, "example.com") || !slices.Contains(info.SANs, "127.0.0.1") {
+				t.Errorf("expected SANs to contain example.com and 127.0.0.1, got %v instead\n", info.SANs)
+			}
+
+			if !info.NotAfter.Equal(tlsServer.Certificate().NotAfter) {
+				t.Errorf("expected expiry %s, got %s instead\n", tlsServer.Certificate().NotAfter, info.NotAfter)
+			}
+
+			if (info.VerifyError != "") != tc.untrusted {
+				t.Errorf("expected the certificate to be untrusted: %t, got verify error %q instead\n", tc.untrusted, info.VerifyError)
+			}
+
+			if res[0].PortStates[1].TLS != nil {
+				t.Errorf("expected no TLS info for the plain port %d, got %+v instead\n", plainPort, res[0].PortStates[1].TLS)
+			}
+		})
+	}
+}
+
+func TestTLSInfoExpiresWithin(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		notAfter time.Time
+		expected bool
+	}{
+		{"Expired", now.AddDate(0, 0, -1), true},
+		{"Soon", now.AddDate(0, 0, 10), true},
+		{"Later", now.AddDate(0, 0, 60), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := scan.TLSInfo{NotAfter: tc.notAfter}
+
+			if info.ExpiresWithin(30*24*time.Hour, now) != tc.expected {
+				t.Errorf("expected ExpiresWithin to be %t\n", tc.expected)
+			}
+		})
+	}
+}
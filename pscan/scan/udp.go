@@ -0,0 +1,98 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// udpProbes holds the protocol specific payloads sent to well known UDP ports.
+// Most UDP services ignore datagrams they do not understand, so an empty payload
+// would leave them indistinguishable from filtered ports.
+var udpProbes = map[int][]byte{
+	// DNS server status request.
+	53: {0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	// NTP v4 client request, the rest of the 48 bytes are left zero.
+	123: append([]byte{0xe3}, make([]byte, 47)...),
+	// SNMP v1 GetRequest of sysDescr.0 with the "public" community.
+	161: {
+		0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x19, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+	},
+}
+
+// scanUDPPort sends a probe to a single UDP port and classifies it based on the answer:
+//   - any response means the port is open
+//   - an ICMP port unreachable, surfaced by the net stack as ECONNREFUSED, means it is closed
+//   - no response within the timeout means it is open or filtered
+func scanUDPPort(ctx context.Context, host string, port int, opts Options) (PortState, error) {
+	p := PortState{
+		Port:     port,
+		Protocol: ProtocolUDP,
+	}
+
+	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	scanConn, err := dial(ctx, opts, "udp", address)
+	if err != nil {
+		if ctx.Err() != nil {
+			return p, ctx.Err()
+		}
+
+		// Dialing UDP does not send anything, an error here means the address or the socket is unusable.
+		p.State, p.Reason = errorState(err)
+		return p, nil
+	}
+
+	defer scanConn.Close()
+
+	// Unblock the read below as soon as ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		scanConn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	start := time.Now()
+	if err := scanConn.SetReadDeadline(start.Add(opts.timeout())); err != nil {
+		// Only context errors stop the scan, see Prober.
+		p.State, p.Reason = errorState(err)
+		return p, nil
+	}
+
+	if _, err := scanConn.Write(udpProbes[port]); err != nil {
+		p.Latency = time.Since(start)
+		p.State, p.Reason = udpState(err)
+		return p, nil
+	}
+
+	buf := make([]byte, 512)
+	_, err = scanConn.Read(buf)
+	p.Latency = time.Since(start)
+
+	if err != nil && ctx.Err() != nil {
+		return p, ctx.Err()
+	}
+
+	p.State, p.Reason = udpState(err)
+	return p, nil
+}
+
+// udpState converts the outcome of a UDP probe into a port state and its reason.
+// Unlike TCP, silence is the expected answer of many open UDP services.
+func udpState(err error) (State, string) {
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return StateOpen, "udp-response"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StateClosed, "port-unreach"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return StateOpenFiltered, "no-response"
+	default:
+		return errorState(err)
+	}
+}
@@ -0,0 +1,130 @@
+package scan_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/acikgozb/cli-playground/pscan/scan"
+)
+
+func TestRunUDP(t *testing.T) {
+	host := "127.0.0.1"
+	hl := scan.HostsList{}
+	hl.Add(host)
+
+	// An echo server answers the probe, so its port is open.
+	echo, err := net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer echo.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	// A silent server never answers, so its port is open or filtered.
+	silent, err := net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer silent.Close()
+
+	// Nothing listens on a closed socket's port, the kernel answers with ICMP port unreachable.
+	closed, err := net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed.Close()
+
+	ports := []int{
+		echo.LocalAddr().(*net.UDPAddr).Port,
+		silent.LocalAddr().(*net.UDPAddr).Port,
+		closed.LocalAddr().(*net.UDPAddr).Port,
+	}
+	expectedStates := []scan.State{scan.StateOpen, scan.StateOpenFiltered, scan.StateClosed}
+
+	res := scan.Run(&hl, ports, scan.Options{UDP: true})
+
+	if len(res) != 1 || len(res[0].PortStates) != len(ports) {
+		t.Fatalf("expected 1 result with %d port states, got %v instead\n", len(ports), res)
+	}
+
+	for i, ps := range res[0].PortStates {
+		if ps.Protocol != scan.ProtocolUDP {
+			t.Errorf("expected protocol %q, got %q instead\n", scan.ProtocolUDP, ps.Protocol)
+		}
+
+		if ps.State != expectedStates[i] {
+			t.Errorf("expected port %d to be %s, got %s instead\n", ps.Port, expectedStates[i], ps.State)
+		}
+	}
+}
+
+// failingDialer fails every dial with err, or hands out closed connections when err is nil.
+type failingDialer struct {
+	err error
+}
+
+func (d failingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: d.err}
+	}
+
+	client, server := net.Pipe()
+	client.Close()
+	server.Close()
+
+	return client, nil
+}
+
+func TestRunUDPErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		dialer         failingDialer
+		expectedReason string
+	}{
+		{"DialError", failingDialer{err: syscall.EMFILE}, "too many open files"},
+		{"DeadlineError", failingDialer{}, "closed pipe"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hl := scan.HostsList{}
+			hl.Add("192.0.2.10")
+
+			opts := scan.Options{
+				UDP:         true,
+				Dialer:      tc.dialer,
+				Resolver:    fakeResolver{addrs: map[string][]string{"192.0.2.10": {"192.0.2.10"}}},
+				NoDiscovery: true,
+			}
+
+			res := scan.Run(&hl, []int{53}, opts)
+
+			// The port is reported with the error instead of being closed or dropped.
+			if len(res) != 1 || len(res[0].PortStates) != 1 {
+				t.Fatalf("expected 1 result with 1 port state, got %v instead\n", res)
+			}
+
+			ps := res[0].PortStates[0]
+			if ps.State != scan.StateError || !strings.Contains(ps.Reason, tc.expectedReason) {
+				t.Errorf("expected state %s with reason %q, got %s (%s) instead\n", scan.StateError, tc.expectedReason, ps.State, ps.Reason)
+			}
+		})
+	}
+}